@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gaply-backend/backend-go/internal/api"
+	"gaply-backend/backend-go/internal/config"
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/maintenance"
+	"gaply-backend/backend-go/internal/spelling"
+	"gaply-backend/backend-go/internal/storage"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to drain
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	conn, err := db.NewConnection(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("failed to create storage client: %v", err)
+	}
+
+	models := db.NewModels(conn)
+	worker := workerclient.NewClient(cfg.WorkerURL)
+	handlers := api.NewHandlers(models, store, worker, cfg)
+
+	runner := jobs.NewRunner(models, jobs.Options{
+		Lease:        cfg.JobLease,
+		PollInterval: cfg.JobPollInterval,
+		BackoffBase:  cfg.JobBackoffBase,
+		BackoffMax:   cfg.JobBackoffMax,
+	})
+	handlers.RegisterJobs(runner)
+
+	// Scheduled runs only report until RECONCILE_DRY_RUN=false
+	reconciler := maintenance.NewReconciler(models, store, maintenance.Options{
+		Interval:    cfg.ReconcileInterval,
+		OrphanGrace: cfg.ReconcileOrphanGrace,
+		StuckAfter:  cfg.ReconcileStuckAfter,
+		DryRun:      cfg.ReconcileDryRun,
+	})
+	handlers.RegisterReconciler(reconciler)
+
+	speller := spelling.NewSuggester(models, spelling.Options{
+		RefreshInterval: cfg.SpellingRefreshInterval,
+		MinCount:        cfg.SpellingMinWordCount,
+		MaxWords:        cfg.SpellingMaxWords,
+	})
+	handlers.RegisterSpeller(speller)
+
+	apiCache := db.NewCache(models)
+	handlers.RegisterCache(apiCache)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	runner.Start(jobsCtx)
+	reconciler.Start(jobsCtx)
+	speller.Start(jobsCtx)
+
+	app := fiber.New(fiber.Config{
+		AppName:      "gaply-api",
+		ErrorHandler: api.ErrorHandler,
+		// Bodies past the limit are streamed rather than rejected, so that
+		// uploads can be let through; registerRoutes enforces each route's limit
+		BodyLimit:         cfg.MaxRequestBytes,
+		StreamRequestBody: true,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	})
+
+	app.Use(recover.New())
+	app.Use(requestid.New())
+	app.Use(logger.New())
+	app.Use(cors.New(cors.Config{
+		AllowOrigins: strings.Join(cfg.AllowedOrigins, ","),
+		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Request-ID",
+	}))
+
+	registerRoutes(app, handlers, cfg)
+
+	// Listen in the background so the main goroutine can wait for a signal
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("gaply-api listening on :%s", cfg.Port)
+		serverErr <- app.Listen(":" + cfg.Port)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			log.Printf("server stopped: %v", err)
+		}
+	case <-ctx.Done():
+		log.Printf("shutdown signal received, draining in-flight requests")
+		if err := app.ShutdownWithTimeout(shutdownTimeout); err != nil {
+			log.Printf("graceful shutdown failed: %v", err)
+		}
+	}
+
+	// In-flight jobs are handed back to the queue for the next instance
+	stopJobs()
+	runner.Wait()
+	reconciler.Wait()
+	speller.Wait()
+	apiCache.Wait()
+
+	log.Printf("closing database connection")
+	conn.Close()
+}
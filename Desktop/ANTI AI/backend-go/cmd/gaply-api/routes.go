@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io"
+
+	"gaply-backend/backend-go/internal/api"
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/config"
+	"gaply-backend/backend-go/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// access describes who may call a route
+type access int
+
+const (
+	// public routes need no credentials
+	public access = iota
+	// user routes require a valid Supabase JWT
+	user
+	// service routes require a JWT with the service_role role
+	service
+	// callback routes require a signed worker callback token
+	callback
+)
+
+// route is a single entry in the route table
+type route struct {
+	method  string
+	path    string
+	access  access
+	handler fiber.Handler
+}
+
+// routeTable lists every endpoint served by the API
+func routeTable(h *api.Handlers) []route {
+	return []route{
+		{fiber.MethodGet, "/health", public, h.Health},
+
+		// Search and paper browsing
+		{fiber.MethodPost, "/api/search", public, h.Search},
+		{fiber.MethodGet, "/api/paper/:id", user, h.GetPaper},
+		{fiber.MethodGet, "/api/paper/:id/evidence", user, h.GetPaperEvidence},
+		{fiber.MethodGet, "/api/paper/:id/summaries", user, h.ListSummaries},
+		{fiber.MethodGet, "/api/paper/:id/summaries/:scope/:granularity", user, h.GetSummary},
+
+		// Local storage objects, authorized by the signed URL's token
+		{fiber.MethodPut, storage.LocalRoutePrefix + "*", public, h.LocalStorageUpload},
+		{fiber.MethodGet, storage.LocalRoutePrefix + "*", public, h.LocalStorageDownload},
+
+		// User features
+		{fiber.MethodPost, "/api/ingest", user, h.Ingest},
+		{fiber.MethodGet, "/api/ingest/:jobId", user, h.GetIngestStatus},
+		{fiber.MethodGet, "/api/jobs/:jobId", user, h.GetJobStatus},
+		{fiber.MethodPost, "/api/paraphrase", user, h.Paraphrase},
+		{fiber.MethodPost, "/api/proofread", user, h.Proofread},
+		{fiber.MethodPost, "/api/journal-check", user, h.JournalCheck},
+		{fiber.MethodPut, "/api/paper/:id/patch", user, h.PatchPaper},
+		{fiber.MethodGet, "/api/paper/:id/edits", user, h.ListPaperEdits},
+		{fiber.MethodPost, "/api/paper/:id/edits/revert", user, h.RevertPaperEdits},
+		{fiber.MethodPost, "/api/paper/:id/summaries", user, h.RequestSummary},
+		{fiber.MethodPost, "/api/gaps", user, h.FindGaps},
+		{fiber.MethodGet, "/api/gaps", user, h.ListGaps},
+		{fiber.MethodGet, "/api/gaps/browse", user, h.BrowseGaps},
+		{fiber.MethodPost, "/api/upload-url", user, h.GetUploadURL},
+		{fiber.MethodPost, "/api/upload/finalize", user, h.FinalizeUpload},
+		{fiber.MethodGet, "/api/library/search", user, h.LibrarySearch},
+		{fiber.MethodGet, "/api/library/hybrid-search", user, h.HybridSearch},
+
+		// Direct worker proxies
+		{fiber.MethodPost, "/worker/ingest", service, h.WorkerIngest},
+		{fiber.MethodPost, "/worker/paraphrase", service, h.WorkerParaphrase},
+		{fiber.MethodPost, "/worker/summarize", service, h.WorkerSummarize},
+		{fiber.MethodPost, "/worker/proofread", service, h.WorkerProofread},
+		{fiber.MethodPost, "/worker/gapfind", service, h.WorkerGapFind},
+		{fiber.MethodPost, "/worker/journal-check", service, h.WorkerJournalCheck},
+		{fiber.MethodPost, "/worker/search-chunks", service, h.WorkerSearchChunks},
+
+		// Worker completion callbacks
+		{fiber.MethodPost, "/worker/callback/:jobId", callback, h.WorkerIngestCallback},
+
+		// Maintenance
+		{fiber.MethodPost, "/admin/reconcile", service, h.Reconcile},
+	}
+}
+
+// registerRoutes mounts the route table on the app with the right middleware
+func registerRoutes(app *fiber.App, h *api.Handlers, cfg *config.Config) {
+	requireUser := auth.JWTMiddleware(cfg.JWTSecret)
+	requireService := auth.RequireRole("service_role")
+	requireCallback := auth.CallbackMiddleware(cfg.WorkerCallbackSecret)
+	smallBody := limitBody(cfg.MaxRequestBytes)
+	uploadBody := limitBody(cfg.MaxUploadBytes)
+
+	for _, r := range routeTable(h) {
+		handlers := []fiber.Handler{smallBody}
+		if isUploadRoute(r) {
+			handlers[0] = uploadBody
+		}
+		switch r.access {
+		case user:
+			handlers = append(handlers, requireUser)
+		case service:
+			handlers = append(handlers, requireUser, requireService)
+		case callback:
+			handlers = append(handlers, requireCallback)
+		}
+		handlers = append(handlers, r.handler)
+
+		app.Add(r.method, r.path, handlers...)
+	}
+}
+
+// isUploadRoute reports whether a route receives PDFs in the request body.
+// Only the local backend's upload URL does; other uploads go to Supabase.
+func isUploadRoute(r route) bool {
+	return r.method == fiber.MethodPut && r.path == storage.LocalRoutePrefix+"*"
+}
+
+// limitBody rejects request bodies over limit bytes with 413. Bodies within
+// the server's BodyLimit arrive buffered; larger or chunked ones arrive as a
+// stream, which is read here up to the limit. A rejected body is left unread,
+// so the connection is closed rather than reused.
+func limitBody(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		if !req.IsBodyStream() {
+			return c.Next()
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return fiber.ErrBadRequest
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		req.SetBody(body)
+		return c.Next()
+	}
+}
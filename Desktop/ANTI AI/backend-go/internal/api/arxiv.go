@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// arxivProvider searches the arXiv API. Everything on arXiv is an open access
+// preprint, so it can honor year, type and open access filters but not
+// citation counts.
+type arxivProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// arxivFeed represents the Atom feed the arXiv API returns
+type arxivFeed struct {
+	TotalResults int          `xml:"http://a9.com/-/spec/opensearch/1.1/ totalResults"`
+	Entries      []arxivEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+// arxivEntry represents one paper in an arXiv feed
+type arxivEntry struct {
+	ID        string `xml:"http://www.w3.org/2005/Atom id"`
+	Title     string `xml:"http://www.w3.org/2005/Atom title"`
+	Summary   string `xml:"http://www.w3.org/2005/Atom summary"`
+	Published string `xml:"http://www.w3.org/2005/Atom published"`
+	Authors   []struct {
+		Name string `xml:"http://www.w3.org/2005/Atom name"`
+	} `xml:"http://www.w3.org/2005/Atom author"`
+	DOI   string `xml:"http://arxiv.org/schemas/atom doi"`
+	Links []struct {
+		Href  string `xml:"href,attr"`
+		Title string `xml:"title,attr"`
+	} `xml:"http://www.w3.org/2005/Atom link"`
+}
+
+// Name identifies arXiv in result sources
+func (p *arxivProvider) Name() string { return "arxiv" }
+
+// Search fetches one page of papers from the arXiv API
+func (p *arxivProvider) Search(ctx context.Context, search SearchRequest) (*providerPage, error) {
+	if search.Cursor != "" {
+		return nil, errUnsupportedSearch
+	}
+
+	var clauses []string
+	for _, term := range queryTerms(search.Query) {
+		clauses = append(clauses, "all:"+term)
+	}
+	if len(clauses) == 0 {
+		return &providerPage{}, nil
+	}
+
+	params := url.Values{}
+	params.Set("start", strconv.Itoa(searchOffset(search)))
+	params.Set("max_results", strconv.Itoa(search.Limit))
+
+	if f := search.Filters; f != nil {
+		if usesOpenAlexIDs(f) || f.MinCitations > 0 {
+			return nil, errUnsupportedSearch
+		}
+		if len(f.Types) > 0 {
+			preprints := false
+			for _, t := range f.Types {
+				preprints = preprints || strings.EqualFold(strings.TrimSpace(t), "preprint")
+			}
+			if !preprints {
+				return nil, errUnsupportedSearch
+			}
+		}
+
+		if f.YearFrom != 0 || f.YearTo != 0 {
+			from, to := f.YearFrom, f.YearTo
+			if from == 0 {
+				from = 1991 // arXiv's first year
+			}
+			if to == 0 {
+				to = time.Now().Year()
+			}
+			clauses = append(clauses, fmt.Sprintf("submittedDate:[%d01010000 TO %d12312359]", from, to))
+		}
+		if f.SortBy == "date" {
+			params.Set("sortBy", "submittedDate")
+			params.Set("sortOrder", "descending")
+		}
+	}
+	params.Set("search_query", strings.Join(clauses, " AND "))
+
+	resp, err := providerGet(ctx, p.client, "arXiv", p.baseURL+"/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var feed arxivFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, entry := range feed.Entries {
+		var authors []string
+		for _, author := range entry.Authors {
+			authors = append(authors, author.Name)
+		}
+
+		var year int
+		if len(entry.Published) >= 4 {
+			year, _ = strconv.Atoi(entry.Published[:4])
+		}
+
+		pdfURL := entry.ID
+		for _, link := range entry.Links {
+			if link.Title == "pdf" {
+				pdfURL = link.Href
+			}
+		}
+
+		results = append(results, SearchResult{
+			ID:           entry.ID,
+			Title:        strings.Join(strings.Fields(entry.Title), " "),
+			Authors:      authors,
+			Year:         year,
+			DOI:          doiURL(entry.DOI),
+			OA:           true,
+			PublisherURL: pdfURL,
+			Abstract:     strings.Join(strings.Fields(entry.Summary), " "),
+		})
+	}
+
+	return &providerPage{
+		Results: results,
+		Total:   feed.TotalResults,
+	}, nil
+}
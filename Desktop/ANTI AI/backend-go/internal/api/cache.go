@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"gaply-backend/backend-go/internal/db"
+)
+
+// RegisterCache sets the cache external API responses are kept in. main
+// builds it so it can wait for its background refreshes on shutdown.
+func (h *Handlers) RegisterCache(c *db.Cache) {
+	h.cache = c
+}
+
+// cachePolicy returns how long a source's responses are cached
+func (h *Handlers) cachePolicy(source string) db.CachePolicy {
+	policy := db.CachePolicy{StaleFor: h.config.CacheStaleFor}
+	switch source {
+	case "openalex":
+		policy.TTL = h.config.OpenAlexCacheTTL
+	case "crossref":
+		policy.TTL = h.config.CrossrefCacheTTL
+	case "arxiv":
+		policy.TTL = h.config.ArxivCacheTTL
+	case "semanticscholar":
+		policy.TTL = h.config.SemanticScholarCacheTTL
+	case "unpaywall":
+		policy.TTL = h.config.UnpaywallCacheTTL
+	}
+	return policy
+}
+
+// cachedProviderSearch runs a provider search through the cache, reporting
+// whether the page was a cache hit, stale or a miss. Pages are cached under
+// db.SearchCachePrefix in the shape that prefix documents. Without a cache
+// every search is a miss.
+func (h *Handlers) cachedProviderSearch(ctx context.Context, provider SearchProvider, search SearchRequest) (*providerPage, string, error) {
+	if h.cache == nil {
+		page, err := provider.Search(ctx, search)
+		return page, db.CacheMiss, err
+	}
+
+	key := db.SearchCachePrefix + provider.Name() + ":" + searchCacheKey(search)
+
+	data, status, err := h.cache.GetOrFetch(ctx, key, h.cachePolicy(provider.Name()), func(ctx context.Context) (json.RawMessage, error) {
+		// Stale pages are refreshed outside any request, so bound the call here too
+		ctx, cancel := context.WithTimeout(ctx, h.config.SearchProviderTimeout)
+		defer cancel()
+
+		page, err := provider.Search(ctx, search)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(page)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var page providerPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, "", err
+	}
+	return &page, status, nil
+}
+
+// searchCacheKey identifies a provider request by everything that shapes its
+// results. Queries differing only in case or spacing share a key; Explain
+// only changes how results are presented and is left out.
+func searchCacheKey(search SearchRequest) string {
+	data, _ := json.Marshal(struct {
+		Query   string         `json:"q"`
+		Limit   int            `json:"limit"`
+		Page    int            `json:"page"`
+		Cursor  string         `json:"cursor"`
+		Filters *SearchFilters `json:"filters"`
+	}{
+		Query:   strings.Join(strings.Fields(strings.ToLower(search.Query)), " "),
+		Limit:   search.Limit,
+		Page:    search.Page,
+		Cursor:  search.Cursor,
+		Filters: search.Filters,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// callbackGrace keeps a callback token valid a little past the job's callback deadline
+const callbackGrace = 5 * time.Minute
+
+// ingestCallbackURL builds the signed URL the worker calls when an ingest finishes
+func (h *Handlers) ingestCallbackURL(jobID uuid.UUID) string {
+	expires := time.Now().Add(h.config.WorkerCallbackTimeout + callbackGrace)
+	token := auth.SignCallback(h.config.WorkerCallbackSecret, jobID.String(), expires)
+
+	query := url.Values{}
+	query.Set("expires", fmt.Sprint(expires.Unix()))
+	query.Set("token", token)
+
+	return fmt.Sprintf("%s/worker/callback/%s?%s", h.config.PublicURL, jobID, query.Encode())
+}
+
+// WorkerIngestCallback handles POST /worker/callback/:jobId
+func (h *Handlers) WorkerIngestCallback(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("jobId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	var cb workerclient.IngestCallback
+	if err := c.BodyParser(&cb); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	ctx := c.Context()
+
+	job, err := h.models.GetJobByID(ctx, jobID)
+	if errors.Is(err, db.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Job not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load job",
+		})
+	}
+
+	if job.Type != jobs.TypeIngest {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Job is not an ingest job",
+		})
+	}
+
+	switch job.Status {
+	case db.JobCompleted:
+		// Redelivered callback; the first one already did the work
+		return c.JSON(JobAcceptedResponse{JobID: jobID.String(), Status: job.Status})
+	case db.JobWaiting, db.JobProcessing:
+	default:
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error":  "Job is not awaiting a callback",
+			"status": job.Status,
+		})
+	}
+
+	var payload IngestPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Job payload is corrupt",
+		})
+	}
+
+	result := IngestJobResult{PaperID: payload.PaperID.String()}
+
+	if cb.Status == StatusFailed || cb.Error != "" {
+		message := cb.Error
+		if message == "" {
+			message = "worker reported ingest failure"
+		}
+
+		status, err := h.models.FailWaitingJob(ctx, jobID, message, h.config.JobBackoffBase)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to record ingest failure",
+			})
+		}
+		if status == db.JobDead {
+			if err := h.models.MarkPaperIngested(ctx, payload.PaperID, StatusFailed); err != nil {
+				log.Printf("ingest %s: failed to mark paper failed: %v", jobID, err)
+			}
+		}
+
+		return c.JSON(JobAcceptedResponse{JobID: jobID.String(), Status: status})
+	}
+
+	// Reject the whole delivery before writing anything if a chunk ID is malformed
+	for _, chunk := range cb.Chunks {
+		if _, err := db.ParseChunkID(chunk.ChunkID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	// Chunks and the paper's status commit together, so a failed delivery can
+	// simply be retried
+	chunks := make([]db.Chunk, 0, len(cb.Chunks))
+	for _, chunk := range cb.Chunks {
+		chunks = append(chunks, db.Chunk{
+			ChunkID:        chunk.ChunkID,
+			Page:           chunk.Page,
+			ParagraphIndex: chunk.ParagraphIndex,
+			SentenceIndex:  chunk.SentenceIndex,
+			Text:           chunk.Text,
+		})
+	}
+	if err := h.models.CompleteIngest(ctx, payload.PaperID, chunks); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store chunks",
+		})
+	}
+
+	result.ChunkCount = cb.ChunkCount
+	if result.ChunkCount == 0 {
+		result.ChunkCount = len(cb.Chunks)
+	}
+	result.Summary = cb.Summary
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := h.models.CompleteWaitingJob(ctx, jobID, data); err != nil && !errors.Is(err, db.ErrNotFound) {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to complete job",
+		})
+	}
+
+	return c.JSON(JobAcceptedResponse{JobID: jobID.String(), Status: db.JobCompleted})
+}
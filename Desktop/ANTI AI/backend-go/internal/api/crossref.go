@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// crossrefMaxOffset is the deepest offset Crossref pages to without a cursor
+const crossrefMaxOffset = 10000
+
+// crossrefWorkTypes maps our work types onto Crossref's
+var crossrefWorkTypes = map[string]string{
+	"article":  "journal-article",
+	"thesis":   "dissertation",
+	"preprint": "posted-content",
+}
+
+// crossrefProvider searches the Crossref works API. It can filter by year and
+// type only, and has no open access or citation filters.
+type crossrefProvider struct {
+	baseURL string
+	mailto  string
+	client  *http.Client
+}
+
+// crossrefResponse represents the Crossref works API response
+type crossrefResponse struct {
+	Message struct {
+		TotalResults int            `json:"total-results"`
+		Items        []crossrefWork `json:"items"`
+	} `json:"message"`
+}
+
+// crossrefWork represents a work from the Crossref API
+type crossrefWork struct {
+	DOI    string   `json:"DOI"`
+	Title  []string `json:"title"`
+	Author []struct {
+		Given  string `json:"given"`
+		Family string `json:"family"`
+		Name   string `json:"name"`
+	} `json:"author"`
+	Issued struct {
+		DateParts [][]int `json:"date-parts"`
+	} `json:"issued"`
+	Type         string `json:"type"`
+	Abstract     string `json:"abstract"`
+	ReferencedBy int    `json:"is-referenced-by-count"`
+	URL          string `json:"URL"`
+}
+
+// Name identifies Crossref in result sources
+func (p *crossrefProvider) Name() string { return "crossref" }
+
+// Search fetches one page of works from the Crossref API
+func (p *crossrefProvider) Search(ctx context.Context, search SearchRequest) (*providerPage, error) {
+	offset := searchOffset(search)
+	if search.Cursor != "" || offset > crossrefMaxOffset {
+		return nil, errUnsupportedSearch
+	}
+
+	params := url.Values{}
+	params.Set("query", search.Query)
+	params.Set("rows", strconv.Itoa(search.Limit))
+	params.Set("offset", strconv.Itoa(offset))
+	if p.mailto != "" {
+		params.Set("mailto", p.mailto)
+	}
+
+	if f := search.Filters; f != nil {
+		if usesOpenAlexIDs(f) || f.OAOnly || f.MinCitations > 0 {
+			return nil, errUnsupportedSearch
+		}
+
+		var clauses []string
+		if f.YearFrom != 0 {
+			clauses = append(clauses, fmt.Sprintf("from-pub-date:%d-01-01", f.YearFrom))
+		}
+		if f.YearTo != 0 {
+			clauses = append(clauses, fmt.Sprintf("until-pub-date:%d-12-31", f.YearTo))
+		}
+		for _, t := range f.Types {
+			clauses = append(clauses, "type:"+crossrefWorkTypes[strings.ToLower(strings.TrimSpace(t))])
+		}
+		if len(clauses) > 0 {
+			params.Set("filter", strings.Join(clauses, ","))
+		}
+		if f.SortBy == "date" {
+			params.Set("sort", "published")
+			params.Set("order", "desc")
+		}
+	}
+
+	resp, err := providerGet(ctx, p.client, "Crossref", p.baseURL+"/works?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var crossrefResp crossrefResponse
+	if err := json.NewDecoder(resp.Body).Decode(&crossrefResp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, work := range crossrefResp.Message.Items {
+		var authors []string
+		for _, author := range work.Author {
+			name := strings.TrimSpace(author.Given + " " + author.Family)
+			if name == "" {
+				name = author.Name
+			}
+			if name != "" {
+				authors = append(authors, name)
+			}
+		}
+
+		var title string
+		if len(work.Title) > 0 {
+			title = work.Title[0]
+		}
+
+		var year int
+		if len(work.Issued.DateParts) > 0 && len(work.Issued.DateParts[0]) > 0 {
+			year = work.Issued.DateParts[0][0]
+		}
+
+		results = append(results, SearchResult{
+			ID:           doiURL(work.DOI),
+			Title:        title,
+			Authors:      authors,
+			Year:         year,
+			DOI:          doiURL(work.DOI),
+			PublisherURL: work.URL,
+			IsThesis:     work.Type == "dissertation",
+			Abstract:     stripJATS(work.Abstract),
+			Citations:    work.ReferencedBy,
+		})
+	}
+
+	return &providerPage{
+		Results: results,
+		Total:   crossrefResp.Message.TotalResults,
+	}, nil
+}
+
+var (
+	// jatsTitlePattern matches section headings such as "Abstract"
+	jatsTitlePattern = regexp.MustCompile(`(?s)<jats:title>.*?</jats:title>`)
+	// markupPattern matches any remaining tag
+	markupPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// stripJATS turns a Crossref JATS XML abstract into plain text
+func stripJATS(abstract string) string {
+	text := jatsTitlePattern.ReplaceAllString(abstract, " ")
+	text = markupPattern.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
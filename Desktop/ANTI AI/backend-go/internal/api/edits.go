@@ -0,0 +1,274 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Edit history page limits
+const (
+	defaultEditPageSize = 50
+	maxEditPageSize     = 200
+)
+
+// PatchRequest represents the patch request body. Version is the chunk
+// version the edit was prepared against; when set, a newer version is a conflict
+// even if old_text still matches.
+type PatchRequest struct {
+	ChunkID string `json:"chunk_id"`
+	OldText string `json:"old_text"`
+	NewText string `json:"new_text"`
+	Version int    `json:"version,omitempty"`
+}
+
+// RevertRequest represents the revert request body. ThroughEditID extends the
+// revert to every edit from EditID through it.
+type RevertRequest struct {
+	EditID        string `json:"edit_id"`
+	ThroughEditID string `json:"through_edit_id,omitempty"`
+}
+
+// EditResponse represents a recorded edit
+type EditResponse struct {
+	ID            string          `json:"id"`
+	PaperID       string          `json:"paper_id"`
+	UserID        string          `json:"user_id"`
+	ChunkID       string          `json:"chunk_id"`
+	ChunkVersion  int             `json:"chunk_version"`
+	RevertsEditID string          `json:"reverts_edit_id,omitempty"`
+	Location      db.EditLocation `json:"location"`
+	OldText       string          `json:"old_text"`
+	NewText       string          `json:"new_text"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// PatchPaper handles PUT /api/paper/:id/patch
+// Replaces a chunk's text if old_text (and version, when given) still match
+// it and records the edit. Only papers in the caller's library can be edited.
+func (h *Handlers) PatchPaper(c *fiber.Ctx) error {
+	userID, err := h.editorID(c)
+	if err != nil {
+		return editorError(c, err)
+	}
+
+	var req PatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if _, err := db.ParseChunkID(req.ChunkID); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.NewText == req.OldText {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'new_text' must differ from 'old_text'",
+		})
+	}
+	if req.Version < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'version' must not be negative",
+		})
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	edit := &db.Edit{
+		PaperID: paper.ID,
+		UserID:  userID,
+		ChunkID: req.ChunkID,
+		OldText: req.OldText,
+		NewText: req.NewText,
+	}
+	if err := h.models.ApplyEdit(c.Context(), edit, req.Version); err != nil {
+		return editError(c, err)
+	}
+
+	return c.JSON(editResponse(edit))
+}
+
+// RevertPaperEdits handles POST /api/paper/:id/edits/revert
+// Applies the inverse of one edit, or of a range of edits newest first, as new
+// edits. Reverting a revert redoes the original change. Like patching, it is
+// limited to papers in the caller's library.
+func (h *Handlers) RevertPaperEdits(c *fiber.Ctx) error {
+	userID, err := h.editorID(c)
+	if err != nil {
+		return editorError(c, err)
+	}
+
+	var req RevertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	fromID, err := uuid.Parse(req.EditID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'edit_id' must be an edit UUID",
+		})
+	}
+	var throughID uuid.UUID
+	if req.ThroughEditID != "" {
+		if throughID, err = uuid.Parse(req.ThroughEditID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "'through_edit_id' must be an edit UUID",
+			})
+		}
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	inverses, err := h.models.RevertEdits(c.Context(), paper.ID, userID, fromID, throughID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Edit or chunk not found in this paper",
+			})
+		}
+		return editError(c, err)
+	}
+
+	response := make([]EditResponse, 0, len(inverses))
+	for i := range inverses {
+		response = append(response, editResponse(&inverses[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"paper_id": paper.ID.String(),
+		"edits":    response,
+	})
+}
+
+// ListPaperEdits handles GET /api/paper/:id/edits
+func (h *Handlers) ListPaperEdits(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", defaultEditPageSize)
+	if page < 1 || pageSize < 1 || pageSize > maxEditPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'page' must be positive and 'pageSize' between 1 and %d", maxEditPageSize),
+		})
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	edits, err := h.models.ListEdits(c.Context(), paper.ID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load edit history",
+		})
+	}
+
+	response := []EditResponse{}
+	for i := range edits {
+		response = append(response, editResponse(&edits[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"paper_id": paper.ID.String(),
+		"edits":    response,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// errNoEditor is returned when the token carries no usable user ID
+var errNoEditor = errors.New("token does not identify a user")
+
+// editorID resolves the authenticated user and makes sure edits can reference them
+func (h *Handlers) editorID(c *fiber.Ctx) (uuid.UUID, error) {
+	userID, err := uuid.Parse(auth.GetUserID(c))
+	if err != nil {
+		return uuid.Nil, errNoEditor
+	}
+
+	if err := h.models.EnsureUser(c.Context(), userID, auth.GetUserEmail(c)); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// editorError turns an editorID failure into a response
+func editorError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errNoEditor) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Token does not identify a user",
+		})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to record user",
+	})
+}
+
+// editError turns an edit failure into a response, returning the current text on conflict
+func editError(c *fiber.Ctx, err error) error {
+	var conflict *db.EditConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response := fiber.Map{
+			"error":           "Chunk text has changed; reload and try again",
+			"chunk_id":        conflict.ChunkID,
+			"current_text":    conflict.CurrentText,
+			"current_version": conflict.CurrentVersion,
+		}
+		if conflict.EditID != nil {
+			response["edit_id"] = conflict.EditID.String()
+		}
+		return c.Status(http.StatusConflict).JSON(response)
+	case errors.Is(err, db.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Chunk not found in this paper",
+		})
+	default:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to apply edit",
+		})
+	}
+}
+
+// editResponse converts a stored edit into its response form
+func editResponse(e *db.Edit) EditResponse {
+	var location db.EditLocation
+	_ = json.Unmarshal(e.Location, &location)
+
+	response := EditResponse{
+		ID:           e.ID.String(),
+		PaperID:      e.PaperID.String(),
+		UserID:       e.UserID.String(),
+		ChunkID:      e.ChunkID,
+		ChunkVersion: e.ChunkVersion,
+		Location:     location,
+		OldText:      e.OldText,
+		NewText:      e.NewText,
+		CreatedAt:    e.CreatedAt,
+	}
+	if e.RevertsEditID != nil {
+		response.RevertsEditID = e.RevertsEditID.String()
+	}
+
+	return response
+}
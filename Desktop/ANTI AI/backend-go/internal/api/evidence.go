@@ -0,0 +1,229 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gaply-backend/backend-go/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Evidence request limits
+const (
+	defaultEvidenceContext = 2
+	maxEvidenceContext     = 10
+	maxEvidenceChunkIDs    = 100
+)
+
+// EvidenceResponse represents the evidence response
+type EvidenceResponse struct {
+	PaperID  string            `json:"paper_id"`
+	DOI      string            `json:"doi,omitempty"`
+	Evidence []EvidencePassage `json:"evidence"`
+	Missing  []string          `json:"missing,omitempty"`
+}
+
+// EvidencePassage is a run of cited sentences with the sentences around it
+type EvidencePassage struct {
+	Sentences []EvidenceSentence `json:"sentences"`
+	Before    []EvidenceSentence `json:"before"`
+	After     []EvidenceSentence `json:"after"`
+}
+
+// EvidenceSentence is a single verbatim sentence and its location
+type EvidenceSentence struct {
+	ChunkID   string `json:"chunk_id"`
+	Page      int    `json:"page"`
+	Paragraph int    `json:"paragraph"`
+	Sentence  int    `json:"sentence"`
+	Text      string `json:"text"`
+}
+
+// position orders chunks by page then paragraph
+type position struct {
+	page int
+	para int
+}
+
+func (p position) after(o position) bool {
+	return p.page > o.page || (p.page == o.page && p.para > o.para)
+}
+
+// GetPaperEvidence handles GET /api/paper/:id/evidence
+// Either chunk_ids (comma separated) or a range given by page, or by
+// page_from/para_from and page_to/para_to, selects the sentences to return.
+// context sets how many neighbouring sentences surround each passage.
+func (h *Handlers) GetPaperEvidence(c *fiber.Ctx) error {
+	contextSize := c.QueryInt("context", defaultEvidenceContext)
+	if contextSize < 0 || contextSize > maxEvidenceContext {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'context' must be between 0 and " + strconv.Itoa(maxEvidenceContext),
+		})
+	}
+
+	var chunkIDs []string
+	for _, id := range strings.Split(c.Query("chunk_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			chunkIDs = append(chunkIDs, id)
+		}
+	}
+
+	from, to, hasRange, err := parseEvidenceRange(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	switch {
+	case len(chunkIDs) == 0 && !hasRange:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either 'chunk_ids' or a page range is required",
+		})
+	case len(chunkIDs) > 0 && hasRange:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Use either 'chunk_ids' or a page range, not both",
+		})
+	case len(chunkIDs) > maxEvidenceChunkIDs:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "At most " + strconv.Itoa(maxEvidenceChunkIDs) + " chunk IDs may be requested",
+		})
+	}
+
+	for _, id := range chunkIDs {
+		if _, err := db.ParseChunkID(id); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	chunks, err := h.models.GetChunksByPaperID(c.Context(), paper.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load paper chunks",
+		})
+	}
+
+	response := EvidenceResponse{
+		PaperID:  paper.ID.String(),
+		DOI:      paper.DOI,
+		Evidence: []EvidencePassage{},
+	}
+
+	if hasRange {
+		start, end := -1, -1
+		for i, chunk := range chunks {
+			pos := position{chunk.Page, chunk.ParagraphIndex}
+			if from.after(pos) || pos.after(to) {
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+			end = i
+		}
+		if start >= 0 {
+			response.Evidence = append(response.Evidence, evidencePassage(chunks, start, end, contextSize))
+		}
+		return c.JSON(response)
+	}
+
+	index := make(map[string]int, len(chunks))
+	for i, chunk := range chunks {
+		index[chunk.ChunkID] = i
+	}
+
+	for _, id := range chunkIDs {
+		i, ok := index[id]
+		if !ok {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+		response.Evidence = append(response.Evidence, evidencePassage(chunks, i, i, contextSize))
+	}
+
+	return c.JSON(response)
+}
+
+// parseEvidenceRange reads the optional page/paragraph range from the query string
+func parseEvidenceRange(c *fiber.Ctx) (from, to position, ok bool, err error) {
+	const unbounded = int(^uint(0) >> 1)
+
+	if c.Query("page") != "" {
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 0 {
+			return from, to, false, errInvalidQuery("page")
+		}
+		return position{page, 0}, position{page, unbounded}, true, nil
+	}
+
+	if c.Query("page_from") == "" && c.Query("page_to") == "" {
+		return from, to, false, nil
+	}
+
+	values := map[string]int{"page_from": 0, "para_from": 0, "page_to": unbounded, "para_to": unbounded}
+	for key := range values {
+		if raw := c.Query(key); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 0 {
+				return from, to, false, errInvalidQuery(key)
+			}
+			values[key] = v
+		}
+	}
+
+	from = position{values["page_from"], values["para_from"]}
+	to = position{values["page_to"], values["para_to"]}
+	if from.after(to) {
+		return from, to, false, errors.New("range start is after range end")
+	}
+
+	return from, to, true, nil
+}
+
+// errInvalidQuery reports a malformed numeric query parameter
+func errInvalidQuery(name string) error {
+	return fmt.Errorf("'%s' must be a non-negative integer", name)
+}
+
+// evidencePassage builds the passage for chunks[start..end] with contextSize sentences either side
+func evidencePassage(chunks []db.Chunk, start, end, contextSize int) EvidencePassage {
+	passage := EvidencePassage{
+		Sentences: []EvidenceSentence{},
+		Before:    []EvidenceSentence{},
+		After:     []EvidenceSentence{},
+	}
+
+	for i := max(0, start-contextSize); i < start; i++ {
+		passage.Before = append(passage.Before, evidenceSentence(chunks[i]))
+	}
+	for i := start; i <= end; i++ {
+		passage.Sentences = append(passage.Sentences, evidenceSentence(chunks[i]))
+	}
+	for i := end + 1; i < len(chunks) && i <= end+contextSize; i++ {
+		passage.After = append(passage.After, evidenceSentence(chunks[i]))
+	}
+
+	return passage
+}
+
+// evidenceSentence converts a chunk into its evidence form
+func evidenceSentence(chunk db.Chunk) EvidenceSentence {
+	return EvidenceSentence{
+		ChunkID:   chunk.ChunkID,
+		Page:      chunk.Page,
+		Paragraph: chunk.ParagraphIndex,
+		Sentence:  chunk.SentenceIndex,
+		Text:      chunk.Text,
+	}
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Gap request limits
+const (
+	defaultGapYearsWindow = 5
+	maxGapYearsWindow     = 50
+	maxGapPapers          = 200
+	defaultGapPageSize    = 20
+	maxGapPageSize        = 100
+)
+
+// GapFindRunRequest represents the gap finding request body
+type GapFindRunRequest struct {
+	PaperIDs    []string `json:"paperIds"`
+	Topic       string   `json:"topic"`
+	YearsWindow int      `json:"yearsWindow"`
+	Force       bool     `json:"force"`
+}
+
+// GapListResponse represents a page of gaps for a run
+type GapListResponse struct {
+	Gaps     []GapResponse `json:"gaps"`
+	Total    int           `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"pageSize"`
+	Cached   bool          `json:"cached"`
+}
+
+// GapResponse represents a single stored gap
+type GapResponse struct {
+	ID        string                  `json:"id"`
+	Statement string                  `json:"statement"`
+	Score     float64                 `json:"score"`
+	Rationale string                  `json:"rationale,omitempty"`
+	Evidence  []workerclient.Evidence `json:"evidence"`
+	PaperIDs  []string                `json:"paperIds"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// FindGaps handles POST /api/gaps
+// Returns the stored gaps for the same paper set, topic and window unless
+// force is set; otherwise queues a gap finding job, or joins the run's live one.
+func (h *Handlers) FindGaps(c *fiber.Ctx) error {
+	var req GapFindRunRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	run, err := parseGapRun(req.PaperIDs, req.Topic, req.YearsWindow)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+
+	if !req.Force {
+		gaps, total, err := h.models.ListRunGaps(ctx, run, defaultGapPageSize, 0)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load gaps",
+			})
+		}
+		// A run that found nothing is indistinguishable from no run, so it is recomputed
+		if total > 0 {
+			response := gapListResponse(gaps, total, 1, defaultGapPageSize)
+			response.Cached = true
+			return c.JSON(response)
+		}
+	}
+
+	paperIDs := make([]string, 0, len(run.PaperIDs))
+	for _, id := range run.SortedPaperIDs() {
+		paperIDs = append(paperIDs, id.String())
+	}
+
+	// Concurrent requests for the same run share one gap finding job
+	key := fmt.Sprintf("%s/%d/%s", run.SetKey(), run.YearsWindow, run.Topic)
+	return h.enqueueUnique(c, jobs.TypeGapFind, key, workerclient.GapFindRequest{
+		PaperIDs:    paperIDs,
+		Topic:       run.Topic,
+		YearsWindow: run.YearsWindow,
+	})
+}
+
+// ListGaps handles GET /api/gaps
+// Query: paperIds (comma separated), topic, yearsWindow, page, pageSize.
+func (h *Handlers) ListGaps(c *fiber.Ctx) error {
+	run, err := parseGapRun(strings.Split(c.Query("paperIds"), ","), c.Query("topic"), c.QueryInt("yearsWindow"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", defaultGapPageSize)
+	if page < 1 || pageSize < 1 || pageSize > maxGapPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'page' must be positive and 'pageSize' between 1 and %d", maxGapPageSize),
+		})
+	}
+
+	gaps, total, err := h.models.ListRunGaps(c.Context(), run, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load gaps",
+		})
+	}
+
+	return c.JSON(gapListResponse(gaps, total, page, pageSize))
+}
+
+// BrowseGaps handles GET /api/gaps/browse
+// Query: paperIds (comma separated), match (any, all or exact; default any),
+// optional topic, page, pageSize. Searches gaps across every stored run.
+func (h *Handlers) BrowseGaps(c *fiber.Ctx) error {
+	filter := db.GapFilter{
+		Match: db.GapMatch(c.Query("match", string(db.GapMatchAny))),
+		Topic: strings.TrimSpace(c.Query("topic")),
+	}
+
+	switch filter.Match {
+	case db.GapMatchAny, db.GapMatchAll, db.GapMatchExact:
+	default:
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'match' must be one of any, all or exact",
+		})
+	}
+
+	for _, raw := range strings.Split(c.Query("paperIds"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid paper ID %q", raw),
+			})
+		}
+		filter.PaperIDs = append(filter.PaperIDs, id)
+	}
+	if len(filter.PaperIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'paperIds' must not be empty",
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", defaultGapPageSize)
+	if page < 1 || pageSize < 1 || pageSize > maxGapPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'page' must be positive and 'pageSize' between 1 and %d", maxGapPageSize),
+		})
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	gaps, total, err := h.models.GetGapsByPaperIDs(c.Context(), filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load gaps",
+		})
+	}
+
+	return c.JSON(gapListResponse(gaps, total, page, pageSize))
+}
+
+// processGapFind runs a queued gap finding job and stores the ranked gaps
+func (h *Handlers) processGapFind(ctx context.Context, task *jobs.Task) (interface{}, error) {
+	var req workerclient.GapFindRequest
+	if err := task.Decode(&req); err != nil {
+		return nil, err
+	}
+
+	run, err := parseGapRun(req.PaperIDs, req.Topic, req.YearsWindow)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	resp, err := h.worker.FindGaps(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps := make([]db.Gap, 0, len(resp.Gaps))
+	for _, g := range resp.Gaps {
+		evidence, err := json.Marshal(g.Evidence)
+		if err != nil {
+			return nil, jobs.Permanent(err)
+		}
+		gaps = append(gaps, db.Gap{
+			Statement: g.Statement,
+			Evidence:  evidence,
+			Score:     clampGapScore(g.Score),
+			Rationale: g.Rationale,
+		})
+	}
+
+	if err := h.models.ReplaceGaps(ctx, run, gaps); err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{
+		"setKey":   run.SetKey(),
+		"gapCount": len(gaps),
+	}, nil
+}
+
+// parseGapRun validates a paper set, topic and years window
+func parseGapRun(rawIDs []string, topic string, yearsWindow int) (db.GapRun, error) {
+	run := db.GapRun{Topic: strings.TrimSpace(topic), YearsWindow: yearsWindow}
+
+	for _, raw := range rawIDs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return run, fmt.Errorf("invalid paper ID %q", raw)
+		}
+		run.PaperIDs = append(run.PaperIDs, id)
+	}
+
+	if len(run.PaperIDs) == 0 {
+		return run, fmt.Errorf("'paperIds' must not be empty")
+	}
+	if len(run.PaperIDs) > maxGapPapers {
+		return run, fmt.Errorf("at most %d papers may be compared", maxGapPapers)
+	}
+	if run.Topic == "" {
+		return run, fmt.Errorf("'topic' is required")
+	}
+	if run.YearsWindow == 0 {
+		run.YearsWindow = defaultGapYearsWindow
+	}
+	if run.YearsWindow < 1 || run.YearsWindow > maxGapYearsWindow {
+		return run, fmt.Errorf("'yearsWindow' must be between 1 and %d", maxGapYearsWindow)
+	}
+
+	return run, nil
+}
+
+// clampGapScore fits a worker score into the gaps.score NUMERIC(3,2) column
+func clampGapScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 9.99 {
+		return 9.99
+	}
+	return score
+}
+
+// gapListResponse converts stored gaps into a response page
+func gapListResponse(gaps []db.Gap, total, page, pageSize int) GapListResponse {
+	response := GapListResponse{
+		Gaps:     []GapResponse{},
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	for _, g := range gaps {
+		evidence := []workerclient.Evidence{}
+		_ = json.Unmarshal(g.Evidence, &evidence)
+
+		paperIDs := []string{}
+		_ = json.Unmarshal(g.PaperIDs, &paperIDs)
+
+		response.Gaps = append(response.Gaps, GapResponse{
+			ID:        g.ID.String(),
+			Statement: g.Statement,
+			Score:     g.Score,
+			Rationale: g.Rationale,
+			Evidence:  evidence,
+			PaperIDs:  paperIDs,
+			CreatedAt: g.CreatedAt,
+		})
+	}
+
+	return response
+}
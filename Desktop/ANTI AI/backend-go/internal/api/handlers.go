@@ -1,8 +1,15 @@
 package api
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
 	"gaply-backend/backend-go/internal/config"
 	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/maintenance"
+	"gaply-backend/backend-go/internal/spelling"
 	"gaply-backend/backend-go/internal/storage"
 	"gaply-backend/backend-go/internal/workerclient"
 
@@ -11,19 +18,42 @@ import (
 
 // Handlers holds all API handlers
 type Handlers struct {
-	models        *db.Models
-	storage       *storage.SupabaseClient
-	worker        *workerclient.Client
-	config        *config.Config
+	models  *db.Models
+	storage storage.Backend
+	worker  *workerclient.Client
+	config  *config.Config
+
+	// httpClient is shared by calls to external APIs so connections are reused
+	httpClient *http.Client
+	providers  []SearchProvider
+	// cache holds external API responses once registered; without one
+	// providers are queried directly
+	cache *db.Cache
+
+	// chunkSearcher runs vector searches. It stays nil, making hybrid search
+	// keyword-only, until the worker's Chroma search returns real chunks.
+	chunkSearcher workerclient.ChunkSearcher
+	// library is where hybrid search finds chunks; the models outside tests
+	library libraryIndex
+
+	reconciler *maintenance.Reconciler
+	speller    *spelling.Suggester
 }
 
 // NewHandlers creates a new Handlers instance
-func NewHandlers(models *db.Models, storage *storage.SupabaseClient, worker *workerclient.Client, config *config.Config) *Handlers {
+func NewHandlers(models *db.Models, storage storage.Backend, worker *workerclient.Client, config *config.Config) *Handlers {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
 	return &Handlers{
 		models:  models,
 		storage: storage,
 		worker:  worker,
 		config:  config,
+
+		httpClient: httpClient,
+		providers:  newSearchProviders(config, httpClient),
+
+		library: models,
 	}
 }
 
@@ -52,35 +82,34 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 	})
 }
 
-// GetPaper handles GET /api/paper/:id
-func (h *Handlers) GetPaper(c *fiber.Ctx) error {
-	// TODO: Implement paper retrieval
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
+// Health handles GET /health
+func (h *Handlers) Health(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
 
-// GetPaperEvidence handles GET /api/paper/:id/evidence
-func (h *Handlers) GetPaperEvidence(c *fiber.Ctx) error {
-	// TODO: Implement evidence retrieval
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
+	code := fiber.StatusOK
+	status := "ok"
+	checks := fiber.Map{"database": "ok", "worker": "ok"}
 
-// Ingest handles POST /api/ingest
-func (h *Handlers) Ingest(c *fiber.Ctx) error {
-	// TODO: Implement paper ingestion
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
+	// The API cannot serve anything without the database; a missing worker only degrades it.
+	// Errors name internal hosts, so they are logged rather than returned.
+	if err := h.models.Ping(ctx); err != nil {
+		log.Printf("health: database check failed: %v", err)
+		code = fiber.StatusServiceUnavailable
+		status = "unavailable"
+		checks["database"] = "unhealthy"
+	}
+	if err := h.worker.HealthCheck(ctx); err != nil {
+		log.Printf("health: worker check failed: %v", err)
+		if code == fiber.StatusOK {
+			status = "degraded"
+		}
+		checks["worker"] = "unhealthy"
+	}
 
-// GetIngestStatus handles GET /api/ingest/:jobId
-func (h *Handlers) GetIngestStatus(c *fiber.Ctx) error {
-	// TODO: Implement job status retrieval
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
+	return c.Status(code).JSON(fiber.Map{
+		"status": status,
+		"checks": checks,
 	})
 }
 
@@ -100,38 +129,6 @@ func (h *Handlers) Proofread(c *fiber.Ctx) error {
 	})
 }
 
-// JournalCheck handles POST /api/journal-check
-func (h *Handlers) JournalCheck(c *fiber.Ctx) error {
-	// TODO: Implement journal compliance check
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
-// PatchPaper handles PUT /api/paper/:id/patch
-func (h *Handlers) PatchPaper(c *fiber.Ctx) error {
-	// TODO: Implement paper patching
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
-// GetUploadURL handles POST /api/upload-url
-func (h *Handlers) GetUploadURL(c *fiber.Ctx) error {
-	// TODO: Implement upload URL generation
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
-// WorkerIngest handles POST /worker/ingest
-func (h *Handlers) WorkerIngest(c *fiber.Ctx) error {
-	// TODO: Implement worker ingest
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
 // WorkerParaphrase handles POST /worker/paraphrase
 func (h *Handlers) WorkerParaphrase(c *fiber.Ctx) error {
 	// TODO: Implement worker paraphrase
@@ -140,14 +137,6 @@ func (h *Handlers) WorkerParaphrase(c *fiber.Ctx) error {
 	})
 }
 
-// WorkerSummarize handles POST /worker/summarize
-func (h *Handlers) WorkerSummarize(c *fiber.Ctx) error {
-	// TODO: Implement worker summarize
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
 // WorkerProofread handles POST /worker/proofread
 func (h *Handlers) WorkerProofread(c *fiber.Ctx) error {
 	// TODO: Implement worker proofread
@@ -155,27 +144,3 @@ func (h *Handlers) WorkerProofread(c *fiber.Ctx) error {
 		"error": "Not implemented yet",
 	})
 }
-
-// WorkerGapFind handles POST /worker/gapfind
-func (h *Handlers) WorkerGapFind(c *fiber.Ctx) error {
-	// TODO: Implement worker gap finding
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
-// WorkerJournalCheck handles POST /worker/journal-check
-func (h *Handlers) WorkerJournalCheck(c *fiber.Ctx) error {
-	// TODO: Implement worker journal check
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
-
-// WorkerSearchChunks handles POST /worker/search-chunks
-func (h *Handlers) WorkerSearchChunks(c *fiber.Ctx) error {
-	// TODO: Implement worker search chunks
-	return c.Status(501).JSON(fiber.Map{
-		"error": "Not implemented yet",
-	})
-}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Hybrid search limits
+const (
+	defaultHybridLimit = 20
+	maxHybridLimit     = 100
+	// hybridDepth is how many candidates each ranking contributes per result returned
+	hybridDepth = 3
+	// rrfK damps the lead of top ranks in reciprocal rank fusion; 60 is the usual choice
+	rrfK = 60
+	// vectorSearchTimeout bounds the worker call; keyword hits are returned without it
+	vectorSearchTimeout = 10 * time.Second
+)
+
+// HybridHit is a chunk found by keyword search, vector search or both.
+// KeywordRank and VectorRank are its 1-based positions in each ranking; zero
+// means that ranking did not return it. Score is the fused RRF score.
+type HybridHit struct {
+	ChunkID     string   `json:"chunk_id"`
+	PaperID     string   `json:"paper_id"`
+	PaperTitle  string   `json:"paper_title"`
+	DOI         string   `json:"doi,omitempty"`
+	Page        int      `json:"page"`
+	Paragraph   int      `json:"paragraph"`
+	Sentence    int      `json:"sentence"`
+	Text        string   `json:"text"`
+	Highlight   string   `json:"highlight,omitempty"`
+	Score       float64  `json:"score"`
+	KeywordRank int      `json:"keyword_rank,omitempty"`
+	VectorRank  int      `json:"vector_rank,omitempty"`
+	Distance    *float64 `json:"distance,omitempty"`
+}
+
+// errNoVectorSearch is returned when no chunk searcher is registered
+var errNoVectorSearch = errors.New("vector search is not available")
+
+// libraryIndex is what hybrid search reads from the library: keyword hits,
+// the papers in a library and the chunks vector hits point at
+type libraryIndex interface {
+	SearchLibrary(ctx context.Context, tsquery string, userID *uuid.UUID, limit, offset int) ([]db.LibraryHit, int, error)
+	LibraryPaperIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	GetLibraryChunks(ctx context.Context, chunkIDs []string, userID *uuid.UUID) ([]db.LibraryHit, error)
+}
+
+// RegisterChunkSearcher sets the vector search backend, such as the worker
+// once its Chroma search is implemented, or a workerclient.FakeChunkSearcher
+func (h *Handlers) RegisterChunkSearcher(s workerclient.ChunkSearcher) {
+	h.chunkSearcher = s
+}
+
+// HybridSearch handles GET /api/library/hybrid-search
+// Fuses Postgres keyword rank with the worker's vector similarity using
+// reciprocal rank fusion, over the same papers as library search. q uses the
+// library search syntax; limit caps the hits returned. Without a chunk
+// searcher, or when it fails, keyword hits are returned alone with keywordOnly set.
+func (h *Handlers) HybridSearch(c *fiber.Ctx) error {
+	query := c.Query("q")
+	tsquery, err := libraryTSQuery(query)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	limit := c.QueryInt("limit", defaultHybridLimit)
+	if limit < 1 || limit > maxHybridLimit {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'limit' must be between 1 and %d", maxHybridLimit),
+		})
+	}
+
+	scope, err := libraryScope(c)
+	if err != nil {
+		return editorError(c, err)
+	}
+
+	ctx := c.Context()
+	depth := limit * hybridDepth
+
+	keywordHits, _, err := h.library.SearchLibrary(ctx, tsquery, scope, depth, 0)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search library",
+		})
+	}
+
+	vectorHits, err := h.vectorSearch(ctx, query, scope, depth)
+	keywordOnly := err != nil
+	if err != nil && !errors.Is(err, errNoVectorSearch) {
+		log.Printf("hybrid search: vector search failed, using keyword hits only: %v", err)
+	}
+
+	hits, err := h.fuseHits(ctx, keywordHits, vectorHits, scope)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load matching chunks",
+		})
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return c.JSON(fiber.Map{
+		"hits":        hits,
+		"keywordOnly": keywordOnly,
+	})
+}
+
+// vectorSearch asks the chunk searcher for chunks similar to the query,
+// restricted to the library when scope is set
+func (h *Handlers) vectorSearch(ctx context.Context, query string, scope *uuid.UUID, depth int) ([]workerclient.ChunkMatch, error) {
+	if h.chunkSearcher == nil {
+		return nil, errNoVectorSearch
+	}
+
+	req := workerclient.SearchChunksRequest{Query: query, Limit: depth}
+	if scope != nil {
+		paperIDs, err := h.library.LibraryPaperIDs(ctx, *scope)
+		if err != nil {
+			return nil, err
+		}
+		if len(paperIDs) == 0 {
+			return nil, nil
+		}
+		for _, id := range paperIDs {
+			req.PaperIDs = append(req.PaperIDs, id.String())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vectorSearchTimeout)
+	defer cancel()
+
+	resp, err := h.chunkSearcher.SearchChunks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// fuseHits merges the keyword and vector rankings with reciprocal rank
+// fusion, best first. Vector hits are only kept when the chunk exists in the
+// scope, which also gives them their paper provenance.
+func (h *Handlers) fuseHits(ctx context.Context, keywordHits []db.LibraryHit, vectorHits []workerclient.ChunkMatch, scope *uuid.UUID) ([]HybridHit, error) {
+	fused := make(map[string]*HybridHit)
+	for i, hit := range keywordHits {
+		fused[hit.ChunkID] = &HybridHit{
+			ChunkID:     hit.ChunkID,
+			PaperID:     hit.PaperID.String(),
+			PaperTitle:  hit.PaperTitle,
+			DOI:         hit.DOI,
+			Page:        hit.Page,
+			Paragraph:   hit.ParagraphIndex,
+			Sentence:    hit.SentenceIndex,
+			Text:        hit.Text,
+			Highlight:   highlightHTML(hit.Headline),
+			KeywordRank: i + 1,
+		}
+	}
+
+	// Ranks count distinct chunks, as the worker may return one several times
+	vectorRanks := make(map[string]int)
+	distances := make(map[string]float64)
+	var missing []string
+	for _, match := range vectorHits {
+		if _, seen := vectorRanks[match.ChunkID]; seen {
+			continue
+		}
+		vectorRanks[match.ChunkID] = len(vectorRanks) + 1
+		distances[match.ChunkID] = match.Distance
+		if fused[match.ChunkID] == nil {
+			missing = append(missing, match.ChunkID)
+		}
+	}
+
+	if len(missing) > 0 {
+		chunks, err := h.library.GetLibraryChunks(ctx, missing, scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			fused[chunk.ChunkID] = &HybridHit{
+				ChunkID:    chunk.ChunkID,
+				PaperID:    chunk.PaperID.String(),
+				PaperTitle: chunk.PaperTitle,
+				DOI:        chunk.DOI,
+				Page:       chunk.Page,
+				Paragraph:  chunk.ParagraphIndex,
+				Sentence:   chunk.SentenceIndex,
+				Text:       chunk.Text,
+			}
+		}
+	}
+
+	hits := make([]HybridHit, 0, len(fused))
+	for chunkID, hit := range fused {
+		if rank, ok := vectorRanks[chunkID]; ok {
+			hit.VectorRank = rank
+			distance := distances[chunkID]
+			hit.Distance = &distance
+		}
+		if hit.KeywordRank > 0 {
+			hit.Score += 1 / float64(rrfK+hit.KeywordRank)
+		}
+		if hit.VectorRank > 0 {
+			hit.Score += 1 / float64(rrfK+hit.VectorRank)
+		}
+		hits = append(hits, *hit)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ChunkID < hits[j].ChunkID
+	})
+	return hits, nil
+}
+
+// WorkerSearchChunks handles POST /worker/search-chunks
+// Passes a vector search straight to the chunk searcher and returns its
+// matches, or 501 while none is registered.
+func (h *Handlers) WorkerSearchChunks(c *fiber.Ctx) error {
+	var req workerclient.SearchChunksRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'query' is required",
+		})
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultHybridLimit
+	}
+	if req.Limit < 0 || req.Limit > maxHybridLimit*hybridDepth {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'n_results' must be between 1 and %d", maxHybridLimit*hybridDepth),
+		})
+	}
+
+	if h.chunkSearcher == nil {
+		return c.Status(http.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Vector search is not implemented yet",
+		})
+	}
+
+	resp, err := h.chunkSearcher.SearchChunks(c.Context(), req)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Worker chunk search failed",
+		})
+	}
+
+	if resp.Results == nil {
+		resp.Results = []workerclient.ChunkMatch{}
+	}
+	return c.JSON(resp)
+}
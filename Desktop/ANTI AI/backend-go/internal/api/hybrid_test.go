@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// fakeLibrary is an in-memory libraryIndex. Keyword search returns hits in
+// order; chunks are visible to a user when their paper is in owned.
+type fakeLibrary struct {
+	hits   []db.LibraryHit
+	chunks []db.LibraryHit
+	owned  []uuid.UUID
+}
+
+func (f *fakeLibrary) SearchLibrary(ctx context.Context, tsquery string, userID *uuid.UUID, limit, offset int) ([]db.LibraryHit, int, error) {
+	hits := f.hits
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, len(f.hits), nil
+}
+
+func (f *fakeLibrary) LibraryPaperIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return f.owned, nil
+}
+
+func (f *fakeLibrary) GetLibraryChunks(ctx context.Context, chunkIDs []string, userID *uuid.UUID) ([]db.LibraryHit, error) {
+	var found []db.LibraryHit
+	for _, chunk := range f.chunks {
+		if !slices.Contains(chunkIDs, chunk.ChunkID) {
+			continue
+		}
+		if userID != nil && !slices.Contains(f.owned, chunk.PaperID) {
+			continue
+		}
+		found = append(found, chunk)
+	}
+	return found, nil
+}
+
+// failingSearcher stands in for a worker that is down
+type failingSearcher struct{}
+
+func (failingSearcher) SearchChunks(ctx context.Context, req workerclient.SearchChunksRequest) (*workerclient.SearchChunksResponse, error) {
+	return nil, errors.New("worker unavailable")
+}
+
+// rankedHit is the part of a HybridHit that fusion decides
+type rankedHit struct {
+	ChunkID     string
+	KeywordRank int
+	VectorRank  int
+}
+
+func ranked(hits []HybridHit) []rankedHit {
+	var out []rankedHit
+	for _, hit := range hits {
+		out = append(out, rankedHit{hit.ChunkID, hit.KeywordRank, hit.VectorRank})
+	}
+	return out
+}
+
+func TestFuseHits(t *testing.T) {
+	mine, theirs := uuid.New(), uuid.New()
+	user := uuid.New()
+
+	chunk := func(id string, paper uuid.UUID) db.LibraryHit {
+		return db.LibraryHit{ChunkID: id, PaperID: paper, PaperTitle: "Paper " + id, Text: "text of " + id}
+	}
+	library := &fakeLibrary{
+		chunks: []db.LibraryHit{chunk("k1", mine), chunk("k2", mine), chunk("k3", mine), chunk("v1", mine), chunk("v2", mine), chunk("x1", theirs)},
+		owned:  []uuid.UUID{mine},
+	}
+	keyword := []db.LibraryHit{chunk("k1", mine), chunk("k2", mine), chunk("k3", mine)}
+	match := func(id string, distance float64) workerclient.ChunkMatch {
+		return workerclient.ChunkMatch{ChunkID: id, Distance: distance}
+	}
+
+	tests := []struct {
+		name    string
+		keyword []db.LibraryHit
+		vector  []workerclient.ChunkMatch
+		scope   *uuid.UUID
+		want    []rankedHit
+	}{
+		{
+			name:    "chunks found by both rank first",
+			keyword: keyword,
+			vector:  []workerclient.ChunkMatch{match("k3", 0.1), match("v1", 0.2)},
+			scope:   &user,
+			// k2 and v1 tie at 1/62 and fall back to chunk ID order
+			want: []rankedHit{{"k3", 3, 1}, {"k1", 1, 0}, {"k2", 2, 0}, {"v1", 0, 2}},
+		},
+		{
+			name:   "repeated vector hits keep their first rank",
+			vector: []workerclient.ChunkMatch{match("v1", 0.1), match("v1", 0.3), match("v2", 0.4)},
+			scope:  &user,
+			want:   []rankedHit{{"v1", 0, 1}, {"v2", 0, 2}},
+		},
+		{
+			name:    "vector hits outside the library are dropped",
+			keyword: keyword[:1],
+			vector:  []workerclient.ChunkMatch{match("x1", 0.1), match("mock-chunk-1", 0.1), match("v1", 0.2)},
+			scope:   &user,
+			want:    []rankedHit{{"k1", 1, 0}, {"v1", 0, 3}},
+		},
+		{
+			name:    "service tokens see every paper",
+			keyword: keyword[:1],
+			vector:  []workerclient.ChunkMatch{match("x1", 0.1)},
+			want:    []rankedHit{{"k1", 1, 0}, {"x1", 0, 1}},
+		},
+		{
+			name:    "keyword hits alone",
+			keyword: keyword,
+			scope:   &user,
+			want:    []rankedHit{{"k1", 1, 0}, {"k2", 2, 0}, {"k3", 3, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{library: library}
+			hits, err := h.fuseHits(context.Background(), tt.keyword, tt.vector, tt.scope)
+			if err != nil {
+				t.Fatalf("fuseHits: %v", err)
+			}
+			if got := ranked(hits); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for _, hit := range hits {
+				if (hit.VectorRank > 0) != (hit.Distance != nil) {
+					t.Errorf("%s: vector rank %d with distance %v", hit.ChunkID, hit.VectorRank, hit.Distance)
+				}
+				if hit.PaperTitle == "" {
+					t.Errorf("%s: missing paper provenance", hit.ChunkID)
+				}
+			}
+		})
+	}
+
+	h := &Handlers{library: library}
+	hits, _ := h.fuseHits(context.Background(), nil, []workerclient.ChunkMatch{match("v1", 0.1), match("v1", 0.3)}, &user)
+	if len(hits) != 1 || *hits[0].Distance != 0.1 {
+		t.Errorf("repeated vector hit should keep its first distance, got %+v", hits)
+	}
+}
+
+func TestHybridSearch(t *testing.T) {
+	mine, theirs := uuid.New(), uuid.New()
+	user := uuid.New()
+
+	library := &fakeLibrary{
+		hits: []db.LibraryHit{
+			{ChunkID: "k1", PaperID: mine, PaperTitle: "Gaps", Text: "research gaps in climate models", Headline: "research " + db.HeadlineStart + "gaps" + db.HeadlineStop},
+			{ChunkID: "k2", PaperID: mine, PaperTitle: "Gaps", Text: "open gaps remain"},
+		},
+		chunks: []db.LibraryHit{
+			{ChunkID: "v1", PaperID: mine, PaperTitle: "Climate", Text: "climate gaps persist"},
+		},
+		owned: []uuid.UUID{mine},
+	}
+	searcher := workerclient.NewFakeChunkSearcher(
+		workerclient.ChunkMatch{ChunkID: "v1", PaperID: mine.String(), Text: "climate gaps persist"},
+		workerclient.ChunkMatch{ChunkID: "k2", PaperID: mine.String(), Text: "open gaps remain"},
+		workerclient.ChunkMatch{ChunkID: "x1", PaperID: theirs.String(), Text: "climate gaps elsewhere"},
+	)
+
+	tests := []struct {
+		name        string
+		searcher    workerclient.ChunkSearcher
+		query       string
+		wantHits    []rankedHit
+		keywordOnly bool
+	}{
+		{
+			name:     "fuses keyword and vector hits",
+			searcher: searcher,
+			query:    "?q=climate+gaps&limit=3",
+			wantHits: []rankedHit{{"k2", 2, 2}, {"k1", 1, 0}, {"v1", 0, 1}},
+		},
+		{
+			name:     "limit cuts the fused list",
+			searcher: searcher,
+			query:    "?q=climate+gaps&limit=1",
+			wantHits: []rankedHit{{"k2", 2, 2}},
+		},
+		{
+			name:        "no chunk searcher means keyword hits only",
+			query:       "?q=climate+gaps&limit=3",
+			wantHits:    []rankedHit{{"k1", 1, 0}, {"k2", 2, 0}},
+			keywordOnly: true,
+		},
+		{
+			name:        "worker failure falls back to keyword hits",
+			searcher:    failingSearcher{},
+			query:       "?q=climate+gaps&limit=3",
+			wantHits:    []rankedHit{{"k1", 1, 0}, {"k2", 2, 0}},
+			keywordOnly: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{library: library}
+			h.RegisterChunkSearcher(tt.searcher)
+
+			app := fiber.New()
+			app.Get("/hybrid", func(c *fiber.Ctx) error {
+				c.Locals("user_id", user.String())
+				return h.HybridSearch(c)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/hybrid"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d", resp.StatusCode)
+			}
+
+			var body struct {
+				Hits        []HybridHit `json:"hits"`
+				KeywordOnly bool        `json:"keywordOnly"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got := ranked(body.Hits); !reflect.DeepEqual(got, tt.wantHits) {
+				t.Errorf("hits = %v, want %v", got, tt.wantHits)
+			}
+			if body.KeywordOnly != tt.keywordOnly {
+				t.Errorf("keywordOnly = %v, want %v", body.KeywordOnly, tt.keywordOnly)
+			}
+		})
+	}
+}
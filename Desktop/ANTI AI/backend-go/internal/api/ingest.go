@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Ingest statuses stored in papers.ingest_status
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// IngestRequest represents the ingest request body
+type IngestRequest struct {
+	DOI         string `json:"doi,omitempty"`
+	StoragePath string `json:"storage_path,omitempty"`
+}
+
+// IngestPayload is the queued payload of an ingest job
+type IngestPayload struct {
+	PaperID     uuid.UUID `json:"paperId"`
+	DOI         string    `json:"doi,omitempty"`
+	StoragePath string    `json:"storage_path,omitempty"`
+}
+
+// IngestJobResponse is returned when an ingest job is accepted
+type IngestJobResponse struct {
+	JobID   string `json:"job_id"`
+	PaperID string `json:"paper_id"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// IngestJobResult is stored in jobs.result and reported by the status endpoint
+type IngestJobResult struct {
+	PaperID    string `json:"paperId"`
+	ChunkCount int    `json:"chunkCount"`
+	Summary    string `json:"summary,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Ingest handles POST /api/ingest
+// A storage_path must have been issued to the caller by /api/upload-url or
+// belong to a paper in their library.
+func (h *Handlers) Ingest(c *fiber.Ctx) error {
+	var req IngestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.DOI = normalizeDOI(req.DOI)
+	req.StoragePath = strings.TrimSpace(req.StoragePath)
+	if req.DOI == "" && req.StoragePath == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either 'doi' or 'storage_path' is required",
+		})
+	}
+
+	ctx := c.Context()
+
+	if req.StoragePath != "" {
+		allowed, err := h.canUseStoragePath(c, req.StoragePath)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check storage path",
+			})
+		}
+		if !allowed {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"error": "'storage_path' was not issued to you and is not in your library",
+			})
+		}
+	}
+
+	paper, err := h.findOrCreateIngestPaper(ctx, req)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create paper record",
+		})
+	}
+	h.addToLibrary(c, paper.ID)
+
+	return h.startIngest(c, paper, IngestPayload{
+		PaperID:     paper.ID,
+		DOI:         req.DOI,
+		StoragePath: req.StoragePath,
+	})
+}
+
+// startIngest queues an ingest job for a paper unless it is already ingested.
+// While a paper has a live ingest job, callers are given that job instead.
+func (h *Handlers) startIngest(c *fiber.Ctx, paper *db.Paper, payload IngestPayload) error {
+	if paper.IngestStatus == StatusCompleted {
+		return c.Status(http.StatusOK).JSON(IngestJobResponse{
+			PaperID: paper.ID.String(),
+			Status:  StatusCompleted,
+			Message: "Paper is already ingested",
+		})
+	}
+
+	job, created, err := h.models.EnqueueUniqueJob(c.Context(), jobs.TypeIngest, paper.ID.String(), payload, h.config.JobMaxAttempts, auth.GetUserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create ingest job",
+		})
+	}
+
+	response := IngestJobResponse{
+		JobID:   job.JobID.String(),
+		PaperID: paper.ID.String(),
+		Status:  job.Status,
+	}
+	if !created {
+		response.Message = "Paper is already being ingested"
+	}
+	return c.Status(http.StatusAccepted).JSON(response)
+}
+
+// canUseStoragePath reports whether the caller may ingest the object at path:
+// service tokens always, users when the path was issued to them for upload or
+// a paper in their library holds it
+func (h *Handlers) canUseStoragePath(c *fiber.Ctx, objectPath string) (bool, error) {
+	if auth.GetUserRole(c) == "service_role" {
+		return true, nil
+	}
+	userID, err := uuid.Parse(auth.GetUserID(c))
+	if err != nil {
+		return false, nil
+	}
+
+	ctx := c.Context()
+	issued, err := h.models.UploadIssued(ctx, objectPath, auth.GetUserID(c))
+	if err != nil || issued {
+		return issued, err
+	}
+	return h.models.StoragePathInLibrary(ctx, userID, objectPath)
+}
+
+// GetIngestStatus handles GET /api/ingest/:jobId
+func (h *Handlers) GetIngestStatus(c *fiber.Ctx) error {
+	return h.GetJobStatus(c)
+}
+
+// findOrCreateIngestPaper reuses the paper for a known DOI or creates a pending one
+func (h *Handlers) findOrCreateIngestPaper(ctx context.Context, req IngestRequest) (*db.Paper, error) {
+	if req.DOI != "" {
+		paper, err := h.models.GetPaperByDOI(ctx, req.DOI)
+		if err == nil {
+			return paper, nil
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	// Title is required by the schema; the worker fills in real metadata later
+	title := req.DOI
+	if title == "" {
+		title = path.Base(req.StoragePath)
+	}
+
+	paper := &db.Paper{
+		DOI:          req.DOI,
+		Title:        title,
+		IngestStatus: StatusPending,
+	}
+	if req.StoragePath != "" {
+		paper.StoragePath = &req.StoragePath
+	}
+
+	if err := h.models.CreatePaper(ctx, paper); err != nil {
+		return nil, err
+	}
+	return paper, nil
+}
+
+// processIngest runs a queued ingest job against the worker
+func (h *Handlers) processIngest(ctx context.Context, task *jobs.Task) (interface{}, error) {
+	var payload IngestPayload
+	if err := task.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	result := IngestJobResult{PaperID: payload.PaperID.String()}
+	if err := task.Progress(ctx, 10, result); err != nil {
+		return nil, err
+	}
+	if err := h.models.UpdatePaperStatus(ctx, payload.PaperID, StatusProcessing); err != nil {
+		return nil, err
+	}
+
+	resp, err := h.worker.IngestPaper(ctx, workerclient.IngestRequest{
+		DOI:            payload.DOI,
+		StoragePath:    payload.StoragePath,
+		NotifyCallback: h.ingestCallbackURL(task.JobID),
+	})
+	if err != nil {
+		// Leave the paper processing while retries remain so pollers don't see a false failure
+		if task.LastAttempt() {
+			if mErr := h.models.MarkPaperIngested(ctx, payload.PaperID, StatusFailed); mErr != nil {
+				log.Printf("ingest %s: failed to mark paper failed: %v", task.JobID, mErr)
+			}
+		}
+		return nil, err
+	}
+
+	// Long ingests finish in the worker's background and report back through the callback
+	if resp.Status != StatusCompleted {
+		if err := task.Progress(ctx, 30, result); err != nil {
+			return nil, err
+		}
+		return nil, jobs.AwaitCallback(result, h.config.WorkerCallbackTimeout)
+	}
+
+	if err := h.models.MarkPaperIngested(ctx, payload.PaperID, StatusCompleted); err != nil {
+		return nil, err
+	}
+
+	result.ChunkCount = resp.ChunkCount
+	result.Summary = resp.Summary
+	return result, nil
+}
+
+// normalizeDOI trims whitespace and resolver prefixes so DOIs compare equal
+func normalizeDOI(doi string) string {
+	doi = strings.TrimSpace(doi)
+	lower := strings.ToLower(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			doi = doi[len(prefix):]
+			break
+		}
+	}
+	return strings.ToLower(doi)
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// JobAcceptedResponse is returned when a job is queued
+type JobAcceptedResponse struct {
+	JobID   string `json:"job_id"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// JobStatusResponse represents the job status response
+type JobStatusResponse struct {
+	JobID       string          `json:"job_id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Progress    int             `json:"progress"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   *string         `json:"last_error,omitempty"`
+	RunAt       time.Time       `json:"run_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// RegisterJobs attaches the worker-backed job handlers to the queue runner
+func (h *Handlers) RegisterJobs(r *jobs.Runner) {
+	r.Register(jobs.TypeIngest, h.config.JobConcurrencyIngest, h.processIngest)
+	r.Register(jobs.TypeSummarize, h.config.JobConcurrencySummarize, h.processSummarize)
+	r.Register(jobs.TypeGapFind, h.config.JobConcurrencyGapFind, h.processGapFind)
+	r.Register(jobs.TypeJournalCheck, h.config.JobConcurrencyJournalCheck, h.processJournalCheck)
+}
+
+// GetJobStatus handles GET /api/jobs/:jobId
+// Only the user who queued the job and service tokens can see it; anyone else
+// gets 404, so job IDs cannot be probed.
+func (h *Handlers) GetJobStatus(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("jobId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	job, err := h.models.GetJobByID(c.Context(), jobID)
+	if err == nil && !canViewJob(c, job) {
+		err = db.ErrNotFound
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Job not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load job",
+		})
+	}
+
+	return c.JSON(JobStatusResponse{
+		JobID:       job.JobID.String(),
+		Type:        job.Type,
+		Status:      job.Status,
+		Progress:    job.Progress,
+		Result:      job.Result,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		RunAt:       job.RunAt,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	})
+}
+
+// canViewJob reports whether the caller queued or joined the job, or holds a
+// service token
+func canViewJob(c *fiber.Ctx, job *db.Job) bool {
+	if auth.GetUserRole(c) == "service_role" {
+		return true
+	}
+	userID := auth.GetUserID(c)
+	if userID == "" {
+		return false
+	}
+	return (job.CreatedBy != nil && *job.CreatedBy == userID) || slices.Contains(job.SharedWith, userID)
+}
+
+// JournalCheck handles POST /api/journal-check
+func (h *Handlers) JournalCheck(c *fiber.Ctx) error {
+	var req workerclient.JournalCheckRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.PaperID == "" && req.Text == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either 'paperId' or 'text' is required",
+		})
+	}
+
+	return h.enqueue(c, jobs.TypeJournalCheck, req)
+}
+
+// WorkerIngest handles POST /worker/ingest
+func (h *Handlers) WorkerIngest(c *fiber.Ctx) error {
+	return h.Ingest(c)
+}
+
+// WorkerSummarize handles POST /worker/summarize
+func (h *Handlers) WorkerSummarize(c *fiber.Ctx) error {
+	var req workerclient.SummarizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.PaperID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'paperId' is required",
+		})
+	}
+
+	return h.enqueue(c, jobs.TypeSummarize, req)
+}
+
+// WorkerGapFind handles POST /worker/gapfind
+func (h *Handlers) WorkerGapFind(c *fiber.Ctx) error {
+	var req workerclient.GapFindRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.PaperIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'paperIds' must not be empty",
+		})
+	}
+
+	return h.enqueue(c, jobs.TypeGapFind, req)
+}
+
+// WorkerJournalCheck handles POST /worker/journal-check
+func (h *Handlers) WorkerJournalCheck(c *fiber.Ctx) error {
+	return h.JournalCheck(c)
+}
+
+// enqueue queues a job and responds with 202 and its ID
+func (h *Handlers) enqueue(c *fiber.Ctx, jobType string, payload interface{}) error {
+	job, err := h.models.EnqueueJob(c.Context(), jobType, payload, h.config.JobMaxAttempts, auth.GetUserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue job",
+		})
+	}
+
+	return c.Status(http.StatusAccepted).JSON(JobAcceptedResponse{
+		JobID:  job.JobID.String(),
+		Status: job.Status,
+	})
+}
+
+// enqueueUnique queues a job like enqueue unless a live job of the same type
+// already has dedupeKey, in which case the caller is given that job
+func (h *Handlers) enqueueUnique(c *fiber.Ctx, jobType, dedupeKey string, payload interface{}) error {
+	job, created, err := h.models.EnqueueUniqueJob(c.Context(), jobType, dedupeKey, payload, h.config.JobMaxAttempts, auth.GetUserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue job",
+		})
+	}
+
+	response := JobAcceptedResponse{
+		JobID:  job.JobID.String(),
+		Status: job.Status,
+	}
+	if !created {
+		response.Message = "An identical job is already running"
+	}
+	return c.Status(http.StatusAccepted).JSON(response)
+}
+
+// processJournalCheck runs a queued journal check job against the worker
+func (h *Handlers) processJournalCheck(ctx context.Context, task *jobs.Task) (interface{}, error) {
+	var req workerclient.JournalCheckRequest
+	if err := task.Decode(&req); err != nil {
+		return nil, err
+	}
+	return h.worker.CheckJournal(ctx, req)
+}
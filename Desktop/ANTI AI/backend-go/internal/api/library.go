@@ -0,0 +1,199 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Library search paging and query limits
+const (
+	defaultLibraryPageSize = 20
+	maxLibraryPageSize     = 100
+	maxLibraryTerms        = 32
+)
+
+// LibraryHit is a chunk of an ingested paper matching a library search.
+// Highlight holds the matching fragments, HTML-escaped, with matches in <mark>.
+type LibraryHit struct {
+	ChunkID    string  `json:"chunk_id"`
+	PaperID    string  `json:"paper_id"`
+	PaperTitle string  `json:"paper_title"`
+	DOI        string  `json:"doi,omitempty"`
+	Page       int     `json:"page"`
+	Paragraph  int     `json:"paragraph"`
+	Sentence   int     `json:"sentence"`
+	Highlight  string  `json:"highlight"`
+	Rank       float64 `json:"rank"`
+}
+
+// LibrarySearch handles GET /api/library/search
+// Searches the text of papers in the caller's library; service tokens search
+// every paper. q takes words, which must all match, "quoted phrases" and
+// prefixes ending in * such as transform*. Paged with page and pageSize.
+func (h *Handlers) LibrarySearch(c *fiber.Ctx) error {
+	tsquery, err := libraryTSQuery(c.Query("q"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", defaultLibraryPageSize)
+	if page < 1 || pageSize < 1 || pageSize > maxLibraryPageSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("'page' must be positive and 'pageSize' between 1 and %d", maxLibraryPageSize),
+		})
+	}
+
+	scope, err := libraryScope(c)
+	if err != nil {
+		return editorError(c, err)
+	}
+
+	hits, total, err := h.models.SearchLibrary(c.Context(), tsquery, scope, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search library",
+		})
+	}
+
+	response := []LibraryHit{}
+	for _, hit := range hits {
+		response = append(response, LibraryHit{
+			ChunkID:    hit.ChunkID,
+			PaperID:    hit.PaperID.String(),
+			PaperTitle: hit.PaperTitle,
+			DOI:        hit.DOI,
+			Page:       hit.Page,
+			Paragraph:  hit.ParagraphIndex,
+			Sentence:   hit.SentenceIndex,
+			Highlight:  highlightHTML(hit.Headline),
+			Rank:       hit.Rank,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"hits":     response,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// libraryTSQuery turns a library search into a tsquery. Words are ANDed,
+// quoted phrases and hyphenated words must appear in order, and a trailing *
+// matches the last word by prefix. Only letters and digits reach the tsquery,
+// so user input cannot break its syntax.
+func libraryTSQuery(q string) (string, error) {
+	var terms []string
+	addTerm := func(text string) {
+		prefix := strings.HasSuffix(text, "*")
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			return
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+		if len(words) == 1 {
+			terms = append(terms, words[0])
+		} else {
+			terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+		}
+	}
+
+	for rest := strings.TrimSpace(q); rest != ""; rest = strings.TrimSpace(rest) {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				// An unclosed quote runs to the end of the query
+				addTerm(rest[1:])
+				break
+			}
+			addTerm(rest[1 : end+1])
+			rest = rest[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(rest)
+		}
+		addTerm(rest[:end])
+		rest = rest[end:]
+	}
+
+	if len(terms) == 0 {
+		return "", fmt.Errorf("Query parameter 'q' must contain a word to search for")
+	}
+	if len(terms) > maxLibraryTerms {
+		return "", fmt.Errorf("Query parameter 'q' may contain at most %d terms", maxLibraryTerms)
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// libraryScope returns whose library a search covers: the caller's, or nil
+// for service tokens, which may search every paper
+func libraryScope(c *fiber.Ctx) (*uuid.UUID, error) {
+	if auth.GetUserRole(c) == "service_role" {
+		return nil, nil
+	}
+
+	userID, err := uuid.Parse(auth.GetUserID(c))
+	if err != nil {
+		return nil, errNoEditor
+	}
+	return &userID, nil
+}
+
+// highlightHTML escapes a ts_headline result and turns its match markers into <mark>
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, db.HeadlineStart, "<mark>")
+	return strings.ReplaceAll(escaped, db.HeadlineStop, "</mark>")
+}
+
+// canAccessPaper reports whether the caller may read a paper's text or change
+// its content: service tokens always, users only for papers in their library
+func (h *Handlers) canAccessPaper(c *fiber.Ctx, paperID uuid.UUID) (bool, error) {
+	if auth.GetUserRole(c) == "service_role" {
+		return true, nil
+	}
+	userID, err := uuid.Parse(auth.GetUserID(c))
+	if err != nil {
+		return false, nil
+	}
+	return h.models.InLibrary(c.Context(), userID, paperID)
+}
+
+// addToLibrary puts a paper in the calling user's library so library search
+// covers it. Failures are logged rather than failing the request that
+// brought the paper in.
+func (h *Handlers) addToLibrary(c *fiber.Ctx, paperID uuid.UUID) {
+	userID, err := uuid.Parse(auth.GetUserID(c))
+	if err != nil {
+		return
+	}
+
+	ctx := c.Context()
+	err = h.models.EnsureUser(ctx, userID, auth.GetUserEmail(c))
+	if err == nil {
+		err = h.models.AddToLibrary(ctx, userID, paperID)
+	}
+	if err != nil {
+		log.Printf("library: failed to add paper %s for user %s: %v", paperID, userID, err)
+	}
+}
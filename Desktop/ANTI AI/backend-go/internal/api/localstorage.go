@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"gaply-backend/backend-go/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalStorageUpload handles PUT /storage/local/*
+// Accepts a direct upload to a URL issued by the local backend's GetSignedURL.
+func (h *Handlers) LocalStorageUpload(c *fiber.Ctx) error {
+	local, objectPath, err := h.verifyLocalStorage(c, storage.LocalOpUpload)
+	if err != nil {
+		return err
+	}
+	if local == nil {
+		return nil
+	}
+
+	exists, err := local.FileExists(c.Context(), objectPath)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check storage",
+		})
+	}
+	if exists {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Object already exists",
+		})
+	}
+
+	if err := local.Write(objectPath, bytes.NewReader(c.Body())); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store upload",
+		})
+	}
+
+	return c.JSON(fiber.Map{"path": objectPath})
+}
+
+// LocalStorageDownload handles GET /storage/local/*
+// Serves an object through a URL issued by the local backend's GetDownloadURL.
+func (h *Handlers) LocalStorageDownload(c *fiber.Ctx) error {
+	local, objectPath, err := h.verifyLocalStorage(c, storage.LocalOpDownload)
+	if err != nil {
+		return err
+	}
+	if local == nil {
+		return nil
+	}
+
+	file, err := local.Open(c.Context(), objectPath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Object not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read object",
+		})
+	}
+
+	c.Type(path.Ext(objectPath))
+	return c.SendStream(file)
+}
+
+// verifyLocalStorage checks a local storage request's signature. A nil backend
+// means the rejection response has already been written.
+func (h *Handlers) verifyLocalStorage(c *fiber.Ctx, op string) (*storage.LocalBackend, string, error) {
+	local, ok := h.storage.(*storage.LocalBackend)
+	if !ok {
+		return nil, "", c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Local storage is not enabled",
+		})
+	}
+
+	objectPath, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return nil, "", c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid object path",
+		})
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || c.Query("op") != op {
+		return nil, "", c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Missing or invalid storage signature",
+		})
+	}
+
+	if err := local.Verify(op, objectPath, expires, c.Query("token")); err != nil {
+		return nil, "", c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired storage token",
+		})
+	}
+
+	return local, objectPath, nil
+}
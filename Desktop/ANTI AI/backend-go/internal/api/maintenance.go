@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"gaply-backend/backend-go/internal/maintenance"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterReconciler attaches the storage and paper reconciler for the admin endpoint
+func (h *Handlers) RegisterReconciler(r *maintenance.Reconciler) {
+	h.reconciler = r
+}
+
+// Reconcile handles POST /admin/reconcile
+// Runs one reconcile pass and returns its report. Query: dryRun (default true);
+// pass dryRun=false to actually delete and update.
+func (h *Handlers) Reconcile(c *fiber.Ctx) error {
+	if h.reconciler == nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Reconciler is not configured",
+		})
+	}
+
+	report := h.reconciler.Run(c.Context(), c.QueryBool("dryRun", true))
+
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(report)
+}
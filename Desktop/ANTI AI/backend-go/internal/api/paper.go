@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// pdfLinkTTL is how long the signed PDF download link in a paper response stays valid
+const pdfLinkTTL = 15 * time.Minute
+
+// PaperDetailResponse represents the paper detail response
+type PaperDetailResponse struct {
+	ID              string                     `json:"id"`
+	DOI             string                     `json:"doi,omitempty"`
+	Title           string                     `json:"title"`
+	Authors         []string                   `json:"authors"`
+	Year            int                        `json:"year,omitempty"`
+	OAPDFURL        *string                    `json:"oa_pdf_url,omitempty"`
+	IngestStatus    string                     `json:"ingest_status"`
+	Ingested        bool                       `json:"ingested"`
+	IngestedAt      *time.Time                 `json:"ingested_at,omitempty"`
+	Summary         []workerclient.SummaryItem `json:"summary"`
+	Outline         []PageOutline              `json:"outline"`
+	ChunkCount      int                        `json:"chunk_count"`
+	InLibrary       bool                       `json:"in_library"`
+	PDFURL          string                     `json:"pdf_url,omitempty"`
+	PDFURLExpiresAt *time.Time                 `json:"pdf_url_expires_at,omitempty"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	UpdatedAt       time.Time                  `json:"updated_at"`
+}
+
+// PageOutline groups a page's paragraphs
+type PageOutline struct {
+	Page       int                `json:"page"`
+	Paragraphs []ParagraphOutline `json:"paragraphs"`
+}
+
+// ParagraphOutline groups a paragraph's sentences
+type ParagraphOutline struct {
+	Paragraph int               `json:"paragraph"`
+	Sentences []OutlineSentence `json:"sentences"`
+}
+
+// OutlineSentence is a single chunk in the outline
+type OutlineSentence struct {
+	ChunkID  string `json:"chunk_id"`
+	Sentence int    `json:"sentence"`
+	Text     string `json:"text"`
+	Version  int    `json:"version"`
+}
+
+// GetPaper handles GET /api/paper/:id
+// The id may be a paper UUID or a URL-encoded DOI. Papers outside the caller's
+// library come back as metadata only, without their text, summary or PDF link.
+func (h *Handlers) GetPaper(c *fiber.Ctx) error {
+	paper, err := h.lookupPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	ctx := c.Context()
+
+	response := PaperDetailResponse{
+		ID:           paper.ID.String(),
+		DOI:          paper.DOI,
+		Title:        paper.Title,
+		Authors:      decodeAuthors(paper.Authors),
+		Year:         paper.Year,
+		OAPDFURL:     paper.OAPDFURL,
+		IngestStatus: paper.IngestStatus,
+		Ingested:     paper.IngestStatus == StatusCompleted,
+		IngestedAt:   paper.IngestedAt,
+		Summary:      []workerclient.SummaryItem{},
+		Outline:      []PageOutline{},
+		CreatedAt:    paper.CreatedAt,
+		UpdatedAt:    paper.UpdatedAt,
+	}
+
+	allowed, err := h.canAccessPaper(c, paper.ID)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+	if !allowed {
+		return c.JSON(response)
+	}
+	response.InLibrary = true
+
+	chunks, err := h.models.GetChunksByPaperID(ctx, paper.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load paper chunks",
+		})
+	}
+	response.Summary = decodeSummary(paper.Summary)
+	response.Outline = buildOutline(chunks)
+	response.ChunkCount = len(chunks)
+
+	if paper.StoragePath != nil && *paper.StoragePath != "" {
+		expiresAt := time.Now().Add(pdfLinkTTL)
+		link, err := h.storage.GetDownloadURL(ctx, *paper.StoragePath, pdfLinkTTL)
+		if err != nil {
+			// The rest of the paper is still useful without the PDF link
+			log.Printf("paper %s: failed to sign PDF link: %v", paper.ID, err)
+		} else {
+			response.PDFURL = link
+			response.PDFURLExpiresAt = &expiresAt
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// lookupPaper resolves the :id route parameter as a paper UUID or a DOI
+func (h *Handlers) lookupPaper(c *fiber.Ctx) (*db.Paper, error) {
+	raw, err := url.PathUnescape(c.Params("id"))
+	if err != nil || strings.TrimSpace(raw) == "" {
+		return nil, db.ErrNotFound
+	}
+
+	if id, err := uuid.Parse(raw); err == nil {
+		return h.models.GetPaperByID(c.Context(), id)
+	}
+
+	return h.models.GetPaperByDOI(c.Context(), normalizeDOI(raw))
+}
+
+// errNotInLibrary is returned when the caller may not read a paper's text
+var errNotInLibrary = errors.New("paper is not in the caller's library")
+
+// libraryPaper resolves the :id route parameter like lookupPaper and fails
+// with errNotInLibrary unless the caller may read the paper's text
+func (h *Handlers) libraryPaper(c *fiber.Ctx) (*db.Paper, error) {
+	paper, err := h.lookupPaper(c)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := h.canAccessPaper(c, paper.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errNotInLibrary
+	}
+	return paper, nil
+}
+
+// paperLookupError turns a lookupPaper or libraryPaper error into a response
+// without leaking database details
+func paperLookupError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Paper not found",
+		})
+	}
+	if errors.Is(err, errNotInLibrary) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"error": "Paper is not in your library",
+		})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to load paper",
+	})
+}
+
+// decodeAuthors accepts authors stored as plain names or as objects with a name field
+func decodeAuthors(raw json.RawMessage) []string {
+	authors := []string{}
+	if len(raw) == 0 {
+		return authors
+	}
+
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		return append(authors, names...)
+	}
+
+	var objects []struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := json.Unmarshal(raw, &objects); err == nil {
+		for _, o := range objects {
+			if o.Name != "" {
+				authors = append(authors, o.Name)
+			} else if o.DisplayName != "" {
+				authors = append(authors, o.DisplayName)
+			}
+		}
+	}
+
+	return authors
+}
+
+// decodeSummary accepts a summary stored as provenance items or as plain text
+func decodeSummary(raw json.RawMessage) []workerclient.SummaryItem {
+	items := []workerclient.SummaryItem{}
+	if len(raw) == 0 {
+		return items
+	}
+
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return items
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil && text != "" {
+		return []workerclient.SummaryItem{{Text: text, Provenance: []workerclient.Provenance{}}}
+	}
+
+	return []workerclient.SummaryItem{}
+}
+
+// buildOutline groups chunks, already ordered by page, paragraph and sentence
+func buildOutline(chunks []db.Chunk) []PageOutline {
+	outline := []PageOutline{}
+
+	for _, chunk := range chunks {
+		if len(outline) == 0 || outline[len(outline)-1].Page != chunk.Page {
+			outline = append(outline, PageOutline{Page: chunk.Page})
+		}
+		page := &outline[len(outline)-1]
+
+		if len(page.Paragraphs) == 0 || page.Paragraphs[len(page.Paragraphs)-1].Paragraph != chunk.ParagraphIndex {
+			page.Paragraphs = append(page.Paragraphs, ParagraphOutline{Paragraph: chunk.ParagraphIndex})
+		}
+		paragraph := &page.Paragraphs[len(page.Paragraphs)-1]
+
+		paragraph.Sentences = append(paragraph.Sentences, OutlineSentence{
+			ChunkID:  chunk.ChunkID,
+			Sentence: chunk.SentenceIndex,
+			Text:     chunk.Text,
+			Version:  chunk.Version,
+		})
+	}
+
+	return outline
+}
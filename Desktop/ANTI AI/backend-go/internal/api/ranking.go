@@ -0,0 +1,176 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/config"
+)
+
+// BM25 parameters; title terms count titleBoost times as much as abstract terms
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	titleBoost = 2.0
+	// recencyHalfLife is how many years it takes a paper's recency score to halve
+	recencyHalfLife = 10.0
+)
+
+// ScoreBreakdown is each ranking signal's weighted contribution to a result's
+// score; the fields sum to the score
+type ScoreBreakdown struct {
+	Text       float64 `json:"text"`
+	Recency    float64 `json:"recency"`
+	Citations  float64 `json:"citations"`
+	OpenAccess float64 `json:"openAccess"`
+	Ingested   float64 `json:"ingested"`
+}
+
+// rankResults scores results against the query and sorts them best first.
+// Text match and citations are normalized within the result set, so scores
+// compare results of one search rather than across searches.
+func rankResults(results []SearchResult, query string, weights config.RankWeights, explain bool) {
+	if len(results) == 0 {
+		return
+	}
+
+	text := textScores(results, queryTerms(query))
+
+	maxCitations := 0
+	for _, result := range results {
+		if result.Citations > maxCitations {
+			maxCitations = result.Citations
+		}
+	}
+
+	year := time.Now().Year()
+	for i := range results {
+		breakdown := ScoreBreakdown{
+			Text:    weights.Text * text[i],
+			Recency: weights.Recency * recencyScore(results[i].Year, year),
+		}
+		if maxCitations > 0 {
+			breakdown.Citations = weights.Citations *
+				math.Log1p(float64(results[i].Citations)) / math.Log1p(float64(maxCitations))
+		}
+		if results[i].OA {
+			breakdown.OpenAccess = weights.OpenAccess
+		}
+		if results[i].Ingested {
+			breakdown.Ingested = weights.Ingested
+		}
+
+		results[i].Score = breakdown.Text + breakdown.Recency + breakdown.Citations +
+			breakdown.OpenAccess + breakdown.Ingested
+		if explain {
+			b := breakdown
+			results[i].ScoreBreakdown = &b
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// sortResults orders results by a non-relevance sortBy: newest first for
+// "date", alphabetically for "title"
+func sortResults(results []SearchResult, sortBy string) {
+	switch sortBy {
+	case "date":
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Year > results[j].Year
+		})
+	case "title":
+		sort.SliceStable(results, func(i, j int) bool {
+			return strings.ToLower(results[i].Title) < strings.ToLower(results[j].Title)
+		})
+	}
+}
+
+// textScores computes a BM25 score over title and abstract for each result,
+// scaled so the best match in the set scores 1
+func textScores(results []SearchResult, terms []string) []float64 {
+	scores := make([]float64, len(results))
+	if len(terms) == 0 {
+		return scores
+	}
+
+	// Weighted term frequencies and lengths of each result's fields
+	freqs := make([][]float64, len(results))
+	lengths := make([]float64, len(results))
+	docFreq := make([]int, len(terms))
+	var totalLength float64
+	for i, result := range results {
+		freqs[i] = make([]float64, len(terms))
+		fields := []struct {
+			text  string
+			boost float64
+		}{{result.Title, titleBoost}, {result.Abstract, 1}}
+		for _, field := range fields {
+			boost := field.boost
+			for _, word := range strings.Fields(field.text) {
+				word = normalizeWord(word)
+				if word == "" {
+					continue
+				}
+				lengths[i] += boost
+				for t, term := range terms {
+					if matchesTerm(word, []string{term}) {
+						freqs[i][t] += boost
+					}
+				}
+			}
+		}
+		for t := range terms {
+			if freqs[i][t] > 0 {
+				docFreq[t]++
+			}
+		}
+		totalLength += lengths[i]
+	}
+
+	n := float64(len(results))
+	avgLength := totalLength / n
+	if avgLength == 0 {
+		return scores
+	}
+
+	best := 0.0
+	for i := range results {
+		for t := range terms {
+			tf := freqs[i][t]
+			if tf == 0 {
+				continue
+			}
+			df := float64(docFreq[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*lengths[i]/avgLength))
+		}
+		if scores[i] > best {
+			best = scores[i]
+		}
+	}
+
+	if best > 0 {
+		for i := range scores {
+			scores[i] /= best
+		}
+	}
+	return scores
+}
+
+// recencyScore decays from 1 for papers published this year, halving every
+// recencyHalfLife years; papers without a year score 0
+func recencyScore(year, currentYear int) float64 {
+	if year <= 0 {
+		return 0
+	}
+	age := float64(currentYear - year)
+	if age < 0 {
+		age = 0
+	}
+	return math.Exp2(-age / recencyHalfLife)
+}
@@ -3,26 +3,61 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
+	"unicode"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/spelling"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// SearchRequest represents the search request body
+// OpenAlex paging limits
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+	// maxPagedResults is how deep page-based paging may go; past it a cursor is required
+	maxPagedResults = 10000
+)
+
+// SearchRequest represents the search request body. Limit applies to each
+// search provider, so a merged page holds up to one page from every provider
+// less duplicates. Page pages through the first 10,000 results; for deeper
+// sweeps start with cursor "*" and pass each response's nextCursor back, which
+// only OpenAlex supports. Page and cursor cannot be combined. Explain adds each
+// result's score breakdown.
 type SearchRequest struct {
-	Query   string                 `json:"q"`
-	Limit   int                    `json:"limit,omitempty"`
-	Filters map[string]interface{} `json:"filters,omitempty"`
+	Query   string         `json:"q"`
+	Limit   int            `json:"limit,omitempty"`
+	Page    int            `json:"page,omitempty"`
+	Cursor  string         `json:"cursor,omitempty"`
+	Filters *SearchFilters `json:"filters,omitempty"`
+	Explain bool           `json:"explain,omitempty"`
 }
 
-// SearchResponse represents the search response
+// SearchResponse represents the search response. Total is the largest number
+// of matching works any provider reported, not the size of this page.
+// PartialEnrichment is set when open access lookups ran out of time; results
+// with oa_checked false were not looked up. FailedSources lists providers that
+// errored or timed out. Cached is set when every provider that answered was
+// served from the cache; CacheStatus gives each one's hit, stale or miss.
 type SearchResponse struct {
-	Results    []SearchResult `json:"results"`
-	DidYouMean *string        `json:"didYouMean,omitempty"`
-	Total      int            `json:"total"`
+	Results           []SearchResult    `json:"results"`
+	DidYouMean        *string           `json:"didYouMean,omitempty"`
+	Total             int               `json:"total"`
+	Page              int               `json:"page,omitempty"`
+	NextCursor        *string           `json:"nextCursor,omitempty"`
+	PartialEnrichment bool              `json:"partialEnrichment,omitempty"`
+	FailedSources     []string          `json:"failedSources,omitempty"`
+	Cached            bool              `json:"cached"`
+	CacheStatus       map[string]string `json:"cacheStatus,omitempty"`
 }
 
 // SearchResult represents a single search result
@@ -33,11 +68,17 @@ type SearchResult struct {
 	Year         int      `json:"year"`
 	DOI          string   `json:"doi"`
 	OA           bool     `json:"oa"`
+	OAChecked    bool     `json:"oa_checked"`
 	PublisherURL string   `json:"publisher_url"`
 	IsThesis     bool     `json:"is_thesis"`
+	Abstract     string   `json:"abstract,omitempty"`
 	Snippet      string   `json:"snippet"`
+	Citations    int      `json:"citations"`
 	Ingested     bool     `json:"ingested"`
 	Score        float64  `json:"score"`
+	Sources      []string `json:"sources"`
+
+	ScoreBreakdown *ScoreBreakdown `json:"score_breakdown,omitempty"`
 }
 
 // OpenAlexWork represents a work from OpenAlex API
@@ -47,6 +88,7 @@ type OpenAlexWork struct {
 	PublicationYear int              `json:"publication_year"`
 	DOI             string           `json:"doi"`
 	Type            string           `json:"type"`
+	CitedByCount    int              `json:"cited_by_count"`
 	Abstract        map[string][]int `json:"abstract_inverted_index"`
 	Authorships     []struct {
 		Author struct {
@@ -62,21 +104,36 @@ type OpenAlexWork struct {
 type OpenAlexResponse struct {
 	Results []OpenAlexWork `json:"results"`
 	Meta    struct {
-		Count int `json:"count"`
+		Count      int     `json:"count"`
+		NextCursor *string `json:"next_cursor"`
 	} `json:"meta"`
 }
 
 // UnpaywallResponse represents the Unpaywall API response
 type UnpaywallResponse struct {
-	IsOA          bool   `json:"is_oa"`
-	BestOALink    string `json:"best_oa_location,omitempty"`
-	BestOALinkURL string `json:"best_oa_location_url,omitempty"`
+	IsOA           bool `json:"is_oa"`
+	BestOALocation *struct {
+		URL       string `json:"url"`
+		URLForPDF string `json:"url_for_pdf"`
+	} `json:"best_oa_location"`
+}
+
+// unpaywallRecord is what we keep of an Unpaywall lookup, cached per DOI
+type unpaywallRecord struct {
+	IsOA bool   `json:"is_oa"`
+	URL  string `json:"url,omitempty"`
 }
 
 // Search handles the search endpoint
 func (h *Handlers) Search(c *fiber.Ctx) error {
 	var req SearchRequest
 	if err := c.BodyParser(&req); err != nil {
+		var filterErr *FilterError
+		if errors.As(err, &filterErr) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": filterErr.Error(),
+			})
+		}
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
@@ -88,74 +145,146 @@ func (h *Handlers) Search(c *fiber.Ctx) error {
 		})
 	}
 
-	// Set default limit
-	if req.Limit == 0 {
-		req.Limit = 50
+	if err := validateSearchPaging(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	// Search OpenAlex
-	results, err := h.searchOpenAlex(c.Context(), req.Query, req.Limit)
+	sortBy := ""
+	if req.Filters != nil {
+		if _, _, err := req.Filters.OpenAlexParams(); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		sortBy = req.Filters.SortBy
+	}
+
+	// Search every provider and merge what they return
+	page, err := h.federatedSearch(c.Context(), req)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to search OpenAlex",
+			"error": "Failed to search papers",
 		})
 	}
+	results := page.Results
 
-	// Enrich with Unpaywall data
+	// Cut snippets now that each result has its best abstract
 	for i := range results {
-		if results[i].DOI != "" {
-			oa, oaURL, err := h.getUnpaywallData(c.Context(), results[i].DOI)
-			if err == nil {
-				results[i].OA = oa
-				if oa && oaURL != "" {
-					results[i].PublisherURL = oaURL
-				}
-			}
-		}
+		results[i].Snippet = h.generateSnippet(results[i].Abstract, req.Query)
 	}
 
-	// Check if paper is ingested
-	for i := range results {
-		if results[i].DOI != "" {
-			ingested, _ := h.checkIfIngested(c.Context(), results[i].DOI)
-			results[i].Ingested = ingested
-		}
+	// Enrich with Unpaywall data and mark papers we already hold
+	partial := h.enrichOpenAccess(c.Context(), results)
+	h.checkIfIngested(c.Context(), results)
+
+	// Re-rank unless the caller asked for a non-relevance order
+	if sortBy == "" || sortBy == "relevance" {
+		rankResults(results, req.Query, h.config.RankWeights, req.Explain)
+	} else {
+		sortResults(results, sortBy)
 	}
 
 	// Generate "Did you mean" suggestion for common typos
 	didYouMean := h.generateDidYouMean(req.Query)
 
 	response := SearchResponse{
-		Results:    results,
-		Total:      len(results),
-		DidYouMean: didYouMean,
+		Results:           results,
+		Total:             page.Total,
+		DidYouMean:        didYouMean,
+		NextCursor:        page.NextCursor,
+		PartialEnrichment: partial,
+		FailedSources:     page.FailedSources,
+		Cached:            len(page.CacheStatus) > 0,
+		CacheStatus:       page.CacheStatus,
+	}
+	for _, status := range page.CacheStatus {
+		if status == db.CacheMiss {
+			response.Cached = false
+		}
+	}
+	if req.Cursor == "" {
+		response.Page = req.Page
 	}
 
 	return c.JSON(response)
 }
 
-// searchOpenAlex searches the OpenAlex API
-func (h *Handlers) searchOpenAlex(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	url := fmt.Sprintf("%s/works?search=%s&per_page=%d", h.config.OpenAlexBaseURL, query, limit)
+// validateSearchPaging applies paging defaults and rejects out-of-range values
+func validateSearchPaging(req *SearchRequest) error {
+	if req.Limit == 0 {
+		req.Limit = defaultSearchLimit
+	}
+	if req.Limit < 0 || req.Limit > maxSearchLimit {
+		return fmt.Errorf("'limit' must be between 1 and %d", maxSearchLimit)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+	if req.Cursor != "" {
+		if req.Page != 0 {
+			return fmt.Errorf("'page' and 'cursor' cannot be combined")
+		}
+		return nil
 	}
 
-	req.Header.Set("User-Agent", "Gaply/1.0 (https://gaply.in)")
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Page < 0 {
+		return fmt.Errorf("'page' must be positive")
+	}
+	if req.Page*req.Limit > maxPagedResults {
+		return fmt.Errorf("'page' can only reach the first %d results; use 'cursor' to go further", maxPagedResults)
+	}
+	return nil
+}
+
+// openAlexProvider searches the OpenAlex works API, the only provider that
+// supports every filter and cursor paging
+type openAlexProvider struct {
+	baseURL string
+	mailto  string
+	client  *http.Client
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+// Name identifies OpenAlex in result sources
+func (p *openAlexProvider) Name() string { return "openalex" }
+
+// Search fetches one page of works from the OpenAlex API
+func (p *openAlexProvider) Search(ctx context.Context, search SearchRequest) (*providerPage, error) {
+	var filter, sort string
+	if search.Filters != nil {
+		var err error
+		if filter, sort, err = search.Filters.OpenAlexParams(); err != nil {
+			return nil, err
+		}
+	}
+
+	params := url.Values{}
+	params.Set("search", search.Query)
+	if filter != "" {
+		params.Set("filter", filter)
+	}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	params.Set("per_page", strconv.Itoa(search.Limit))
+	if search.Cursor != "" {
+		params.Set("cursor", search.Cursor)
+	} else {
+		params.Set("page", strconv.Itoa(search.Page))
+	}
+	if p.mailto != "" {
+		// Identifies us for OpenAlex's polite pool
+		params.Set("mailto", p.mailto)
+	}
+
+	resp, err := providerGet(ctx, p.client, "OpenAlex", p.baseURL+"/works?"+params.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAlex API returned status: %d", resp.StatusCode)
-	}
-
 	var openAlexResp OpenAlexResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAlexResp); err != nil {
 		return nil, err
@@ -169,8 +298,8 @@ func (h *Handlers) searchOpenAlex(ctx context.Context, query string, limit int)
 			authors = append(authors, authorship.Author.DisplayName)
 		}
 
-		// Generate snippet from abstract
-		snippet := h.generateSnippet(work.Abstract)
+		// Rebuild the abstract from its word positions
+		abstract := reconstructAbstract(work.Abstract)
 
 		// Determine if it's a thesis
 		isThesis := strings.Contains(strings.ToLower(work.Type), "thesis")
@@ -183,108 +312,331 @@ func (h *Handlers) searchOpenAlex(ctx context.Context, query string, limit int)
 			DOI:          work.DOI,
 			PublisherURL: work.HostVenue.URL,
 			IsThesis:     isThesis,
-			Snippet:      snippet,
-			Score:        0.9, // Default score, could be enhanced with relevance scoring
+			Abstract:     abstract,
+			Citations:    work.CitedByCount,
 		}
 
 		results = append(results, result)
 	}
 
-	return results, nil
+	return &providerPage{
+		Results:    results,
+		Total:      openAlexResp.Meta.Count,
+		NextCursor: openAlexResp.Meta.NextCursor,
+	}, nil
 }
 
-// getUnpaywallData gets Open Access information from Unpaywall
-func (h *Handlers) getUnpaywallData(ctx context.Context, doi string) (bool, string, error) {
+// enrichOpenAccess looks up open access status for results with a DOI
+// through a bounded pool of workers. Lookups still running at the configured
+// deadline are abandoned; it reports whether any result was left unchecked.
+func (h *Handlers) enrichOpenAccess(ctx context.Context, results []SearchResult) bool {
 	if h.config.UnpaywallEmail == "" {
-		return false, "", fmt.Errorf("Unpaywall email not configured")
+		return false
 	}
 
-	url := fmt.Sprintf("https://api.unpaywall.org/v2/%s?email=%s", doi, h.config.UnpaywallEmail)
+	ctx, cancel := context.WithTimeout(ctx, h.config.UnpaywallDeadline)
+	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return false, "", err
+	jobs := make(chan int)
+	workers := h.config.UnpaywallConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(results) {
+		workers = len(results)
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				record, err := h.getUnpaywallData(ctx, results[i].DOI)
+				if err != nil {
+					continue
+				}
+				// Providers such as arXiv already know a work is open
+				results[i].OA = results[i].OA || record.IsOA
+				results[i].OAChecked = true
+				if record.IsOA && record.URL != "" {
+					results[i].PublisherURL = record.URL
+				}
+			}
+		}()
+	}
+
+	for i := range results {
+		if results[i].DOI == "" {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range results {
+		if result.DOI != "" && !result.OAChecked {
+			return true
+		}
+	}
+	return false
+}
+
+// getUnpaywallData gets Open Access information for a DOI, reading through
+// the API cache. DOIs Unpaywall does not know are cached as closed access.
+func (h *Handlers) getUnpaywallData(ctx context.Context, doi string) (*unpaywallRecord, error) {
+	doi = normalizeDOI(doi)
+	if h.cache == nil {
+		return h.fetchUnpaywallData(ctx, doi)
+	}
+	cacheKey := "unpaywall:" + strings.ToLower(doi)
+
+	data, _, err := h.cache.GetOrFetch(ctx, cacheKey, h.cachePolicy("unpaywall"), func(ctx context.Context) (json.RawMessage, error) {
+		record, err := h.fetchUnpaywallData(ctx, doi)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(record)
+	})
 	if err != nil {
-		return false, "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, "", fmt.Errorf("Unpaywall API returned status: %d", resp.StatusCode)
+	var record unpaywallRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// fetchUnpaywallData asks the Unpaywall API about a normalized DOI
+func (h *Handlers) fetchUnpaywallData(ctx context.Context, doi string) (*unpaywallRecord, error) {
+	endpoint := "https://api.unpaywall.org/v2/" + (&url.URL{Path: doi}).EscapedPath() +
+		"?" + url.Values{"email": {h.config.UnpaywallEmail}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	var unpaywallResp UnpaywallResponse
-	if err := json.NewDecoder(resp.Body).Decode(&unpaywallResp); err != nil {
-		return false, "", err
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	var oaURL string
-	if unpaywallResp.BestOALinkURL != "" {
-		oaURL = unpaywallResp.BestOALinkURL
-	} else if unpaywallResp.BestOALink != "" {
-		oaURL = unpaywallResp.BestOALink
+	var record unpaywallRecord
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var unpaywallResp UnpaywallResponse
+		if err := json.NewDecoder(resp.Body).Decode(&unpaywallResp); err != nil {
+			return nil, err
+		}
+		record.IsOA = unpaywallResp.IsOA
+		if loc := unpaywallResp.BestOALocation; loc != nil {
+			record.URL = loc.URL
+			if record.URL == "" {
+				record.URL = loc.URLForPDF
+			}
+		}
+	case http.StatusNotFound:
+		// Unknown to Unpaywall; remember that rather than asking again
+	default:
+		return nil, fmt.Errorf("Unpaywall API returned status: %d", resp.StatusCode)
 	}
 
-	return unpaywallResp.IsOA, oaURL, nil
+	return &record, nil
 }
 
-// checkIfIngested checks if a paper is already ingested
-func (h *Handlers) checkIfIngested(ctx context.Context, doi string) (bool, error) {
-	_, err := h.models.GetPaperByDOI(ctx, doi)
+// checkIfIngested marks results whose papers have already been ingested
+func (h *Handlers) checkIfIngested(ctx context.Context, results []SearchResult) {
+	var dois []string
+	for _, result := range results {
+		if result.DOI != "" {
+			dois = append(dois, normalizeDOI(result.DOI))
+		}
+	}
+	if len(dois) == 0 {
+		return
+	}
+
+	ingested, err := h.models.IngestedDOIs(ctx, dois)
 	if err != nil {
-		return false, nil // Paper not found, not ingested
+		return // Treat as not ingested rather than failing the search
+	}
+	for i := range results {
+		if results[i].DOI != "" {
+			results[i].Ingested = ingested[normalizeDOI(results[i].DOI)]
+		}
 	}
-	return true, nil
 }
 
-// generateSnippet generates a snippet from abstract data
-func (h *Handlers) generateSnippet(abstract map[string][]int) string {
-	if abstract == nil {
-		return "Abstract not available"
+// snippetWords is how many words of the abstract a snippet shows
+const snippetWords = 30
+
+// maxAbstractWords bounds the word positions reconstructAbstract accepts, so a
+// bogus position from upstream cannot force a huge allocation
+const maxAbstractWords = 5000
+
+// reconstructAbstract rebuilds abstract text from an OpenAlex inverted index,
+// which maps each word to the positions it occurs at. Words at positions past
+// maxAbstractWords are dropped.
+func reconstructAbstract(inverted map[string][]int) string {
+	last := -1
+	for _, positions := range inverted {
+		for _, pos := range positions {
+			if pos > last && pos < maxAbstractWords {
+				last = pos
+			}
+		}
+	}
+	if last < 0 {
+		return ""
 	}
 
-	// Convert inverted index to text (simplified)
-	var words []string
-	for word := range abstract {
-		words = append(words, word)
-		if len(words) >= 20 { // Limit snippet length
-			break
+	words := make([]string, last+1)
+	for word, positions := range inverted {
+		for _, pos := range positions {
+			if pos >= 0 && pos <= last {
+				words[pos] = word
+			}
+		}
+	}
+
+	// Skip positions the index leaves empty
+	text := make([]string, 0, len(words))
+	for _, word := range words {
+		if word != "" {
+			text = append(text, word)
 		}
 	}
+	return strings.Join(text, " ")
+}
 
+// generateSnippet picks the stretch of the abstract with the most query term
+// matches and wraps the matches in <mark>. The rest of the text is HTML-escaped.
+func (h *Handlers) generateSnippet(abstract, query string) string {
+	words := strings.Fields(abstract)
 	if len(words) == 0 {
 		return "Abstract not available"
 	}
 
-	snippet := strings.Join(words, " ")
-	if len(snippet) > 200 {
-		snippet = snippet[:200] + "..."
+	terms := queryTerms(query)
+	matched := make([]bool, len(words))
+	for i, word := range words {
+		matched[i] = matchesTerm(normalizeWord(word), terms)
 	}
 
-	return snippet
+	// Slide a fixed window over the abstract, keeping the first best one
+	start, best, count := 0, -1, 0
+	for i := range words {
+		if matched[i] {
+			count++
+		}
+		if i >= snippetWords && matched[i-snippetWords] {
+			count--
+		}
+		if i >= snippetWords-1 || i == len(words)-1 {
+			if count > best {
+				best = count
+				start = i - snippetWords + 1
+				if start < 0 {
+					start = 0
+				}
+			}
+		}
+	}
+
+	// Words before the window's first match carry no hits; move the window
+	// forward so that match has only a little lead-in context
+	for i := start; i < start+snippetWords && i < len(words); i++ {
+		if matched[i] {
+			if lead := i - snippetWords/3; lead > start {
+				start = lead
+			}
+			break
+		}
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+		if start = end - snippetWords; start < 0 {
+			start = 0
+		}
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("... ")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			snippet.WriteByte(' ')
+		}
+		if matched[i] {
+			snippet.WriteString("<mark>" + html.EscapeString(words[i]) + "</mark>")
+		} else {
+			snippet.WriteString(html.EscapeString(words[i]))
+		}
+	}
+	if end < len(words) {
+		snippet.WriteString(" ...")
+	}
+
+	return snippet.String()
 }
 
-// generateDidYouMean generates "Did you mean" suggestions for common typos
-func (h *Handlers) generateDidYouMean(query string) *string {
-	// Simple typo detection - could be enhanced with more sophisticated algorithms
-	commonTypos := map[string]string{
-		"machin":      "machine",
-		"learnin":     "learning",
-		"researc":     "research",
-		"artificil":   "artificial",
-		"intelligenc": "intelligence",
-	}
-
-	for typo, correction := range commonTypos {
-		if strings.Contains(strings.ToLower(query), typo) {
-			suggestion := strings.ReplaceAll(strings.ToLower(query), typo, correction)
-			return &suggestion
+// queryTerms splits a query into normalized terms worth highlighting
+func queryTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		if term := normalizeWord(word); len(term) > 1 {
+			terms = append(terms, term)
 		}
 	}
+	return terms
+}
 
-	return nil
+// normalizeWord lowercases a word and trims surrounding punctuation
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// matchesTerm reports whether a normalized word matches a query term; longer
+// terms also match as prefixes so "learn" highlights "learning"
+func matchesTerm(word string, terms []string) bool {
+	if word == "" {
+		return false
+	}
+	for _, term := range terms {
+		if word == term || (len(term) >= 4 && strings.HasPrefix(word, term)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterSpeller attaches the query spelling suggester
+func (h *Handlers) RegisterSpeller(s *spelling.Suggester) {
+	h.speller = s
+}
+
+// generateDidYouMean suggests a corrected query when words in it are
+// unknown to the vocabulary built from our papers and search results
+func (h *Handlers) generateDidYouMean(query string) *string {
+	if h.speller == nil {
+		return nil
+	}
+
+	suggestion, ok := h.speller.Suggest(query)
+	if !ok {
+		return nil
+	}
+	return &suggestion
 }
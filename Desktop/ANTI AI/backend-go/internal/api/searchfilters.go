@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFilterValues caps how many IDs one filter may OR together, matching OpenAlex
+const maxFilterValues = 50
+
+// searchWorkTypes maps our work types onto OpenAlex's
+var searchWorkTypes = map[string]string{
+	"article":  "article",
+	"thesis":   "dissertation",
+	"preprint": "preprint",
+}
+
+// searchSorts maps sortBy onto OpenAlex sort parameters
+var searchSorts = map[string]string{
+	"relevance": "relevance_score:desc",
+	"date":      "publication_date:desc",
+	"title":     "display_name:asc",
+}
+
+// SearchFilters narrows a search. Author, institution, venue and concept
+// filters take OpenAlex IDs, bare ("A5023888391") or as URLs.
+type SearchFilters struct {
+	YearFrom     int      `json:"yearFrom,omitempty"`
+	YearTo       int      `json:"yearTo,omitempty"`
+	OAOnly       bool     `json:"oaOnly,omitempty"`
+	Types        []string `json:"types,omitempty"`
+	Authors      []string `json:"authors,omitempty"`
+	Institutions []string `json:"institutions,omitempty"`
+	Venues       []string `json:"venues,omitempty"`
+	Concepts     []string `json:"concepts,omitempty"`
+	MinCitations int      `json:"minCitations,omitempty"`
+	SortBy       string   `json:"sortBy,omitempty"`
+}
+
+// FilterError explains why a search filter was rejected
+type FilterError struct {
+	msg string
+}
+
+func (e *FilterError) Error() string { return e.msg }
+
+// filterErrorf builds a FilterError
+func filterErrorf(format string, args ...interface{}) error {
+	return &FilterError{msg: fmt.Sprintf(format, args...)}
+}
+
+// UnmarshalJSON rejects unknown filter names instead of silently ignoring them
+func (f *SearchFilters) UnmarshalJSON(data []byte) error {
+	type plain SearchFilters
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var decoded plain
+	if err := decoder.Decode(&decoded); err != nil {
+		return filterErrorf("invalid filters: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	*f = SearchFilters(decoded)
+	return nil
+}
+
+// openAlexIDPattern matches an OpenAlex entity ID with its type prefix
+var openAlexIDPattern = regexp.MustCompile(`(?i)^(?:https?://openalex\.org/)?([AISC])(\d+)$`)
+
+// OpenAlexParams validates the filters and returns the OpenAlex filter and sort
+// parameters; either may be empty
+func (f *SearchFilters) OpenAlexParams() (filter string, sort string, err error) {
+	var clauses []string
+
+	maxYear := time.Now().Year() + 1
+	for _, year := range []struct {
+		name  string
+		value int
+	}{{"yearFrom", f.YearFrom}, {"yearTo", f.YearTo}} {
+		if year.value != 0 && (year.value < 1000 || year.value > maxYear) {
+			return "", "", filterErrorf("'%s' must be a year between 1000 and %d", year.name, maxYear)
+		}
+	}
+	if f.YearFrom != 0 && f.YearTo != 0 && f.YearFrom > f.YearTo {
+		return "", "", filterErrorf("'yearFrom' (%d) is after 'yearTo' (%d)", f.YearFrom, f.YearTo)
+	}
+	if f.YearFrom != 0 {
+		clauses = append(clauses, fmt.Sprintf("from_publication_date:%d-01-01", f.YearFrom))
+	}
+	if f.YearTo != 0 {
+		clauses = append(clauses, fmt.Sprintf("to_publication_date:%d-12-31", f.YearTo))
+	}
+
+	if f.OAOnly {
+		clauses = append(clauses, "is_oa:true")
+	}
+
+	if len(f.Types) > 0 {
+		var types []string
+		for _, t := range f.Types {
+			mapped, ok := searchWorkTypes[strings.ToLower(strings.TrimSpace(t))]
+			if !ok {
+				return "", "", filterErrorf("unknown work type %q; use article, thesis or preprint", t)
+			}
+			types = append(types, mapped)
+		}
+		clauses = append(clauses, "type:"+strings.Join(types, "|"))
+	}
+
+	for _, idFilter := range []struct {
+		name   string
+		prefix string
+		field  string
+		ids    []string
+	}{
+		{"authors", "A", "authorships.author.id", f.Authors},
+		{"institutions", "I", "authorships.institutions.id", f.Institutions},
+		{"venues", "S", "primary_location.source.id", f.Venues},
+		{"concepts", "C", "concepts.id", f.Concepts},
+	} {
+		if len(idFilter.ids) == 0 {
+			continue
+		}
+		clause, err := openAlexIDClause(idFilter.name, idFilter.prefix, idFilter.field, idFilter.ids)
+		if err != nil {
+			return "", "", err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if f.MinCitations < 0 {
+		return "", "", filterErrorf("'minCitations' must not be negative")
+	}
+	if f.MinCitations > 0 {
+		// OpenAlex only has a strict greater-than
+		clauses = append(clauses, "cited_by_count:>"+strconv.Itoa(f.MinCitations-1))
+	}
+
+	if f.SortBy != "" {
+		var ok bool
+		if sort, ok = searchSorts[f.SortBy]; !ok {
+			return "", "", filterErrorf("unknown 'sortBy' %q; use relevance, date or title", f.SortBy)
+		}
+	}
+
+	return strings.Join(clauses, ","), sort, nil
+}
+
+// openAlexIDClause validates OpenAlex IDs of one kind and ORs them into a filter clause
+func openAlexIDClause(name, prefix, field string, ids []string) (string, error) {
+	if len(ids) > maxFilterValues {
+		return "", filterErrorf("'%s' accepts at most %d IDs", name, maxFilterValues)
+	}
+
+	normalized := make([]string, 0, len(ids))
+	for _, id := range ids {
+		match := openAlexIDPattern.FindStringSubmatch(strings.TrimSpace(id))
+		if match == nil || strings.ToUpper(match[1]) != prefix {
+			return "", filterErrorf("'%s' must contain OpenAlex IDs like %s123456, got %q", name, prefix, id)
+		}
+		normalized = append(normalized, prefix+match[2])
+	}
+
+	return field + ":" + strings.Join(normalized, "|"), nil
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"gaply-backend/backend-go/internal/config"
+)
+
+// searchUserAgent identifies us to the scholarly APIs
+const searchUserAgent = "Gaply/1.0 (https://gaply.in)"
+
+// errUnsupportedSearch is returned by providers that cannot honor a request's
+// filters or paging; they are skipped rather than counted as failed, so a
+// filtered search never mixes in unfiltered results
+var errUnsupportedSearch = errors.New("search not supported by provider")
+
+// SearchProvider is an upstream index of scholarly works that Search fans out to
+type SearchProvider interface {
+	// Name identifies the provider in each result's sources
+	Name() string
+	// Search fetches one page of works matching the request
+	Search(ctx context.Context, search SearchRequest) (*providerPage, error)
+}
+
+// providerPage is one page of results from a provider. Snippets are left to
+// Search, which cuts them once results from every provider are merged. Its JSON
+// form is what the cache stores, so "results" and each result's "title" must
+// keep the shape db.SearchCachePrefix documents.
+type providerPage struct {
+	Results    []SearchResult `json:"results"`
+	Total      int            `json:"total"`
+	NextCursor *string        `json:"nextCursor,omitempty"`
+}
+
+// federatedPage is the merged outcome of querying every provider.
+// CacheStatus maps each provider that answered to whether its page was a
+// cache hit, stale or a miss.
+type federatedPage struct {
+	Results       []SearchResult
+	Total         int
+	NextCursor    *string
+	FailedSources []string
+	CacheStatus   map[string]string
+}
+
+// newSearchProviders builds the configured providers in priority order
+func newSearchProviders(cfg *config.Config, client *http.Client) []SearchProvider {
+	var providers []SearchProvider
+	for _, name := range cfg.SearchProviders {
+		switch name {
+		case "openalex":
+			providers = append(providers, &openAlexProvider{
+				baseURL: cfg.OpenAlexBaseURL,
+				mailto:  cfg.UnpaywallEmail,
+				client:  client,
+			})
+		case "crossref":
+			providers = append(providers, &crossrefProvider{
+				baseURL: cfg.CrossrefBaseURL,
+				mailto:  cfg.UnpaywallEmail,
+				client:  client,
+			})
+		case "arxiv":
+			providers = append(providers, &arxivProvider{
+				baseURL: cfg.ArxivBaseURL,
+				client:  client,
+			})
+		case "semanticscholar":
+			providers = append(providers, &semanticScholarProvider{
+				baseURL: cfg.SemanticScholarBaseURL,
+				apiKey:  cfg.SemanticScholarAPIKey,
+				client:  client,
+			})
+		}
+	}
+	return providers
+}
+
+// federatedSearch queries every provider in parallel, each under its own
+// timeout and through the cache, and merges their results. It fails only when
+// no provider answered.
+func (h *Handlers) federatedSearch(ctx context.Context, search SearchRequest) (*federatedPage, error) {
+	type outcome struct {
+		page   *providerPage
+		status string
+		err    error
+	}
+	outcomes := make([]outcome, len(h.providers))
+
+	var wg sync.WaitGroup
+	for i, provider := range h.providers {
+		wg.Add(1)
+		go func(i int, provider SearchProvider) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, h.config.SearchProviderTimeout)
+			defer cancel()
+
+			page, status, err := h.cachedProviderSearch(ctx, provider, search)
+			outcomes[i] = outcome{page: page, status: status, err: err}
+		}(i, provider)
+	}
+	wg.Wait()
+
+	result := &federatedPage{CacheStatus: make(map[string]string)}
+	var pages [][]SearchResult
+	var names []string
+	var lastErr error
+	for i, o := range outcomes {
+		name := h.providers[i].Name()
+		switch {
+		case errors.Is(o.err, errUnsupportedSearch):
+			continue
+		case o.err != nil:
+			log.Printf("search: %s failed: %v", name, o.err)
+			result.FailedSources = append(result.FailedSources, name)
+			lastErr = o.err
+			continue
+		}
+
+		pages = append(pages, o.page.Results)
+		names = append(names, name)
+		result.CacheStatus[name] = o.status
+		// Sources overlap, so the largest count is the best estimate we have
+		if o.page.Total > result.Total {
+			result.Total = o.page.Total
+		}
+		if o.page.NextCursor != nil {
+			result.NextCursor = o.page.NextCursor
+		}
+	}
+
+	if len(names) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("all search providers failed: %w", lastErr)
+	}
+
+	result.Results = mergeResults(pages, names)
+	return result, nil
+}
+
+// mergeResults combines provider pages, in provider priority order, into one
+// list without duplicates. Works match on DOI; a work without a DOI matches a
+// work with the same title and year, as long as one side has no DOI. Merged
+// works keep the first provider's fields and fill gaps from the others.
+func mergeResults(pages [][]SearchResult, names []string) []SearchResult {
+	merged := []SearchResult{}
+	byDOI := make(map[string]int)
+	byTitle := make(map[string]int)
+
+	for p, page := range pages {
+		for _, result := range page {
+			result.Sources = []string{names[p]}
+
+			doiKey := strings.ToLower(normalizeDOI(result.DOI))
+			titleKey := titleYearKey(result.Title, result.Year)
+
+			index, found := -1, false
+			if doiKey != "" {
+				index, found = byDOI[doiKey]
+			}
+			if !found && titleKey != "" {
+				if i, ok := byTitle[titleKey]; ok && (doiKey == "" || merged[i].DOI == "") {
+					index, found = i, true
+				}
+			}
+
+			if found {
+				mergeResult(&merged[index], result)
+			} else {
+				index = len(merged)
+				merged = append(merged, result)
+			}
+
+			if doiKey != "" {
+				if _, ok := byDOI[doiKey]; !ok {
+					byDOI[doiKey] = index
+				}
+			}
+			if titleKey != "" {
+				if _, ok := byTitle[titleKey]; !ok {
+					byTitle[titleKey] = index
+				}
+			}
+		}
+	}
+
+	return merged
+}
+
+// mergeResult folds a duplicate from a lower-priority provider into dst
+func mergeResult(dst *SearchResult, src SearchResult) {
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if len(dst.Authors) == 0 {
+		dst.Authors = src.Authors
+	}
+	if dst.Year == 0 {
+		dst.Year = src.Year
+	}
+	if dst.DOI == "" {
+		dst.DOI = src.DOI
+	}
+	if dst.Abstract == "" {
+		dst.Abstract = src.Abstract
+	}
+	if dst.PublisherURL == "" {
+		dst.PublisherURL = src.PublisherURL
+	}
+	if src.OA && !dst.OA {
+		dst.OA = true
+		if src.PublisherURL != "" {
+			dst.PublisherURL = src.PublisherURL
+		}
+	}
+	if src.Citations > dst.Citations {
+		dst.Citations = src.Citations
+	}
+	dst.IsThesis = dst.IsThesis || src.IsThesis
+
+	for _, source := range src.Sources {
+		if !slices.Contains(dst.Sources, source) {
+			dst.Sources = append(dst.Sources, source)
+		}
+	}
+}
+
+// titleYearKey identifies a work by its normalized title and year
+func titleYearKey(title string, year int) string {
+	var words []string
+	for _, word := range strings.Fields(title) {
+		if word = normalizeWord(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s|%d", strings.Join(words, " "), year)
+}
+
+// doiURL renders a bare DOI in the resolver form OpenAlex returns, so results
+// from every provider look alike
+func doiURL(doi string) string {
+	if doi = normalizeDOI(doi); doi == "" {
+		return ""
+	}
+	return "https://doi.org/" + doi
+}
+
+// usesOpenAlexIDs reports whether the filters name OpenAlex entities, which
+// only OpenAlex can apply
+func usesOpenAlexIDs(f *SearchFilters) bool {
+	return f != nil && (len(f.Authors) > 0 || len(f.Institutions) > 0 || len(f.Venues) > 0 || len(f.Concepts) > 0)
+}
+
+// searchOffset is the zero-based position of the request's first result
+func searchOffset(search SearchRequest) int {
+	return (search.Page - 1) * search.Limit
+}
+
+// providerGet performs a GET against a provider API and checks the status
+func providerGet(ctx context.Context, client *http.Client, name, endpoint string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", searchUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s API returned status: %d", name, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,534 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"gaply-backend/backend-go/internal/config"
+	"gaply-backend/backend-go/internal/db"
+)
+
+// fixtureServer serves body for every request after letting check inspect it
+func fixtureServer(t *testing.T, contentType, body string, check func(r *http.Request)) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != searchUserAgent {
+			t.Errorf("User-Agent = %q, want %q", r.Header.Get("User-Agent"), searchUserAgent)
+		}
+		check(r)
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// unreachableServer fails the test if a provider calls it
+func unreachableServer(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// expectParams checks the query parameters a provider sent
+func expectParams(t *testing.T, r *http.Request, path string, want map[string]string) {
+	t.Helper()
+
+	if r.URL.Path != path {
+		t.Errorf("path = %q, want %q", r.URL.Path, path)
+	}
+	query := r.URL.Query()
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+// checkPage compares a provider page with the results it should hold
+func checkPage(t *testing.T, page *providerPage, total int, want []SearchResult) {
+	t.Helper()
+
+	if page.Total != total {
+		t.Errorf("total = %d, want %d", page.Total, total)
+	}
+	if !reflect.DeepEqual(page.Results, want) {
+		t.Errorf("results = %+v\nwant %+v", page.Results, want)
+	}
+}
+
+const openAlexFixture = `{
+	"results": [{
+		"id": "https://openalex.org/W1",
+		"title": "Research Gaps in Climate Models",
+		"publication_year": 2021,
+		"doi": "https://doi.org/10.1000/gap",
+		"type": "thesis",
+		"cited_by_count": 12,
+		"abstract_inverted_index": {"Models": [0], "still": [1], "disagree": [2, 4], "and": [3]},
+		"authorships": [{"author": {"display_name": "Ada Lovelace"}}],
+		"host_venue": {"url": "https://example.org/w1"}
+	}],
+	"meta": {"count": 42, "next_cursor": "next-page"}
+}`
+
+func TestOpenAlexProviderSearch(t *testing.T) {
+	baseURL := fixtureServer(t, "application/json", openAlexFixture, func(r *http.Request) {
+		expectParams(t, r, "/works", map[string]string{
+			"search":   "climate gaps",
+			"per_page": "10",
+			"page":     "2",
+			"mailto":   "team@example.org",
+			"filter":   "from_publication_date:2020-01-01",
+		})
+	})
+
+	provider := &openAlexProvider{baseURL: baseURL, mailto: "team@example.org", client: http.DefaultClient}
+	page, err := provider.Search(context.Background(), SearchRequest{
+		Query:   "climate gaps",
+		Limit:   10,
+		Page:    2,
+		Filters: &SearchFilters{YearFrom: 2020},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	checkPage(t, page, 42, []SearchResult{{
+		ID:           "https://openalex.org/W1",
+		Title:        "Research Gaps in Climate Models",
+		Authors:      []string{"Ada Lovelace"},
+		Year:         2021,
+		DOI:          "https://doi.org/10.1000/gap",
+		PublisherURL: "https://example.org/w1",
+		IsThesis:     true,
+		Abstract:     "Models still disagree and disagree",
+		Citations:    12,
+	}})
+	if page.NextCursor == nil || *page.NextCursor != "next-page" {
+		t.Errorf("next cursor = %v, want next-page", page.NextCursor)
+	}
+}
+
+const crossrefFixture = `{
+	"message": {
+		"total-results": 7,
+		"items": [{
+			"DOI": "10.1000/XREF",
+			"title": ["Gaps in Crossref Metadata"],
+			"author": [{"given": "Grace", "family": "Hopper"}, {"name": "Gaply Consortium"}],
+			"issued": {"date-parts": [[2019, 5]]},
+			"type": "dissertation",
+			"abstract": "<jats:title>Abstract</jats:title><jats:p>Models &amp; data <jats:italic>differ</jats:italic> widely.</jats:p>",
+			"is-referenced-by-count": 3,
+			"URL": "https://doi.org/10.1000/XREF"
+		}]
+	}
+}`
+
+func TestCrossrefProviderSearch(t *testing.T) {
+	baseURL := fixtureServer(t, "application/json", crossrefFixture, func(r *http.Request) {
+		expectParams(t, r, "/works", map[string]string{
+			"query":  "metadata gaps",
+			"rows":   "10",
+			"offset": "10",
+			"mailto": "team@example.org",
+			"filter": "from-pub-date:2018-01-01,type:dissertation",
+		})
+	})
+
+	provider := &crossrefProvider{baseURL: baseURL, mailto: "team@example.org", client: http.DefaultClient}
+	page, err := provider.Search(context.Background(), SearchRequest{
+		Query:   "metadata gaps",
+		Limit:   10,
+		Page:    2,
+		Filters: &SearchFilters{YearFrom: 2018, Types: []string{"thesis"}},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	checkPage(t, page, 7, []SearchResult{{
+		ID:           "https://doi.org/10.1000/xref",
+		Title:        "Gaps in Crossref Metadata",
+		Authors:      []string{"Grace Hopper", "Gaply Consortium"},
+		Year:         2019,
+		DOI:          "https://doi.org/10.1000/xref",
+		PublisherURL: "https://doi.org/10.1000/XREF",
+		IsThesis:     true,
+		Abstract:     "Models & data differ widely.",
+		Citations:    3,
+	}})
+}
+
+const arxivFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"
+      xmlns:opensearch="http://a9.com/-/spec/opensearch/1.1/"
+      xmlns:arxiv="http://arxiv.org/schemas/atom">
+  <opensearch:totalResults>5</opensearch:totalResults>
+  <entry>
+    <id>http://arxiv.org/abs/2101.00001v1</id>
+    <published>2021-01-04T18:00:00Z</published>
+    <title>Transformers for
+      Gap Finding</title>
+    <summary>  We study
+      open gaps.
+    </summary>
+    <author><name>Alan Turing</name></author>
+    <author><name>Emmy Noether</name></author>
+    <arxiv:doi>10.1000/ARX</arxiv:doi>
+    <link href="http://arxiv.org/abs/2101.00001v1" rel="alternate" type="text/html"/>
+    <link title="pdf" href="http://arxiv.org/pdf/2101.00001v1" rel="related" type="application/pdf"/>
+  </entry>
+</feed>`
+
+func TestArxivProviderSearch(t *testing.T) {
+	baseURL := fixtureServer(t, "application/atom+xml", arxivFixture, func(r *http.Request) {
+		expectParams(t, r, "/query", map[string]string{
+			"search_query": "all:transformer AND all:gaps AND submittedDate:[202001010000 TO 202112312359]",
+			"start":        "0",
+			"max_results":  "5",
+		})
+	})
+
+	provider := &arxivProvider{baseURL: baseURL, client: http.DefaultClient}
+	page, err := provider.Search(context.Background(), SearchRequest{
+		Query:   "Transformer gaps",
+		Limit:   5,
+		Page:    1,
+		Filters: &SearchFilters{YearFrom: 2020, YearTo: 2021, Types: []string{"preprint"}},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	checkPage(t, page, 5, []SearchResult{{
+		ID:           "http://arxiv.org/abs/2101.00001v1",
+		Title:        "Transformers for Gap Finding",
+		Authors:      []string{"Alan Turing", "Emmy Noether"},
+		Year:         2021,
+		DOI:          "https://doi.org/10.1000/arx",
+		OA:           true,
+		PublisherURL: "http://arxiv.org/pdf/2101.00001v1",
+		Abstract:     "We study open gaps.",
+	}})
+}
+
+const semanticScholarFixture = `{
+	"total": 2,
+	"data": [{
+		"paperId": "abc123",
+		"title": "Open Gaps",
+		"abstract": "An open paper.",
+		"year": 2022,
+		"url": "https://www.semanticscholar.org/paper/abc123",
+		"citationCount": 9,
+		"isOpenAccess": false,
+		"openAccessPdf": {"url": "https://example.org/open.pdf"},
+		"externalIds": {"DOI": "10.1000/S2"},
+		"authors": [{"name": "Rosalind Franklin"}]
+	}, {
+		"paperId": "def456",
+		"title": "Closed Gaps",
+		"year": 2020,
+		"citationCount": 1,
+		"externalIds": {},
+		"authors": []
+	}]
+}`
+
+func TestSemanticScholarProviderSearch(t *testing.T) {
+	baseURL := fixtureServer(t, "application/json", semanticScholarFixture, func(r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != "s2-key" {
+			t.Errorf("x-api-key = %q, want s2-key", got)
+		}
+		expectParams(t, r, "/paper/search", map[string]string{
+			"query":            "open gaps",
+			"offset":           "0",
+			"limit":            "100",
+			"fields":           semanticScholarFields,
+			"year":             "2020-",
+			"minCitationCount": "1",
+		})
+	})
+
+	provider := &semanticScholarProvider{baseURL: baseURL, apiKey: "s2-key", client: http.DefaultClient}
+	page, err := provider.Search(context.Background(), SearchRequest{
+		Query:   "open gaps",
+		Limit:   150,
+		Page:    1,
+		Filters: &SearchFilters{YearFrom: 2020, MinCitations: 1},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	checkPage(t, page, 2, []SearchResult{{
+		ID:           "https://www.semanticscholar.org/paper/abc123",
+		Title:        "Open Gaps",
+		Authors:      []string{"Rosalind Franklin"},
+		Year:         2022,
+		DOI:          "https://doi.org/10.1000/s2",
+		OA:           true,
+		PublisherURL: "https://example.org/open.pdf",
+		Abstract:     "An open paper.",
+		Citations:    9,
+	}, {
+		ID:        "https://www.semanticscholar.org/paper/def456",
+		Title:     "Closed Gaps",
+		Year:      2020,
+		Citations: 1,
+	}})
+}
+
+func TestProvidersRejectUnsupportedSearches(t *testing.T) {
+	client := http.DefaultClient
+	tests := []struct {
+		name     string
+		provider func(baseURL string) SearchProvider
+		search   SearchRequest
+	}{
+		{
+			"crossref cursor",
+			func(u string) SearchProvider { return &crossrefProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 10, Cursor: "*"},
+		},
+		{
+			"crossref past max offset",
+			func(u string) SearchProvider { return &crossrefProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 100, Page: 102},
+		},
+		{
+			"crossref open access",
+			func(u string) SearchProvider { return &crossrefProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 10, Page: 1, Filters: &SearchFilters{OAOnly: true}},
+		},
+		{
+			"arxiv theses",
+			func(u string) SearchProvider { return &arxivProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 10, Page: 1, Filters: &SearchFilters{Types: []string{"thesis"}}},
+		},
+		{
+			"arxiv citations",
+			func(u string) SearchProvider { return &arxivProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 10, Page: 1, Filters: &SearchFilters{MinCitations: 5}},
+		},
+		{
+			"semantic scholar OpenAlex IDs",
+			func(u string) SearchProvider { return &semanticScholarProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 10, Page: 1, Filters: &SearchFilters{Authors: []string{"A123"}}},
+		},
+		{
+			"semantic scholar past max results",
+			func(u string) SearchProvider { return &semanticScholarProvider{baseURL: u, client: client} },
+			SearchRequest{Query: "gaps", Limit: 100, Page: 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := tt.provider(unreachableServer(t))
+			if _, err := provider.Search(context.Background(), tt.search); !errors.Is(err, errUnsupportedSearch) {
+				t.Fatalf("err = %v, want errUnsupportedSearch", err)
+			}
+		})
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages [][]SearchResult
+		names []string
+		want  []SearchResult
+	}{
+		{
+			name: "DOI match across providers",
+			pages: [][]SearchResult{
+				{{ID: "W1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/A", Citations: 2}},
+				{{ID: "S1", Title: "Gap finding (preprint)", Year: 2020, DOI: "10.1000/a", Abstract: "Text.", Citations: 5}},
+			},
+			names: []string{"openalex", "semanticscholar"},
+			want: []SearchResult{
+				{ID: "W1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/A", Abstract: "Text.", Citations: 5, Sources: []string{"openalex", "semanticscholar"}},
+			},
+		},
+		{
+			name: "title and year match when one side has no DOI",
+			pages: [][]SearchResult{
+				{{ID: "W1", Title: "Gap Finding", Year: 2021}},
+				{{ID: "X1", Title: "gap finding.", Year: 2021, DOI: "https://doi.org/10.1000/b", OA: true, PublisherURL: "https://example.org/b.pdf"}},
+			},
+			names: []string{"openalex", "arxiv"},
+			want: []SearchResult{
+				{ID: "W1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/b", OA: true, PublisherURL: "https://example.org/b.pdf", Sources: []string{"openalex", "arxiv"}},
+			},
+		},
+		{
+			name: "same title in another year stays separate",
+			pages: [][]SearchResult{
+				{{ID: "W1", Title: "Gap Finding", Year: 2021}},
+				{{ID: "X1", Title: "Gap Finding", Year: 2019}},
+			},
+			names: []string{"openalex", "arxiv"},
+			want: []SearchResult{
+				{ID: "W1", Title: "Gap Finding", Year: 2021, Sources: []string{"openalex"}},
+				{ID: "X1", Title: "Gap Finding", Year: 2019, Sources: []string{"arxiv"}},
+			},
+		},
+		{
+			name: "different DOIs with the same title stay separate",
+			pages: [][]SearchResult{
+				{{ID: "W1", Title: "Introduction", Year: 2021, DOI: "https://doi.org/10.1000/one"}},
+				{{ID: "C1", Title: "Introduction", Year: 2021, DOI: "https://doi.org/10.1000/two"}},
+			},
+			names: []string{"openalex", "crossref"},
+			want: []SearchResult{
+				{ID: "W1", Title: "Introduction", Year: 2021, DOI: "https://doi.org/10.1000/one", Sources: []string{"openalex"}},
+				{ID: "C1", Title: "Introduction", Year: 2021, DOI: "https://doi.org/10.1000/two", Sources: []string{"crossref"}},
+			},
+		},
+		{
+			name: "sources follow provider priority without repeats",
+			pages: [][]SearchResult{
+				{{ID: "W1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/a"}},
+				{
+					{ID: "C1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/a"},
+					{ID: "C2", Title: "Other Work", Year: 2020},
+				},
+				{
+					{ID: "X2", Title: "Other Work", Year: 2020},
+					{ID: "X1", Title: "Gap Finding", Year: 2021},
+				},
+			},
+			names: []string{"openalex", "crossref", "arxiv"},
+			want: []SearchResult{
+				{ID: "W1", Title: "Gap Finding", Year: 2021, DOI: "https://doi.org/10.1000/a", Sources: []string{"openalex", "crossref", "arxiv"}},
+				{ID: "C2", Title: "Other Work", Year: 2020, Sources: []string{"crossref", "arxiv"}},
+			},
+		},
+		{
+			name:  "no pages",
+			pages: nil,
+			names: nil,
+			want:  []SearchResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeResults(tt.pages, tt.names)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubProvider answers every search with a fixed page or error
+type stubProvider struct {
+	name string
+	page *providerPage
+	err  error
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Search(ctx context.Context, search SearchRequest) (*providerPage, error) {
+	return p.page, p.err
+}
+
+func TestFederatedSearchSkipsUnsupportedProviders(t *testing.T) {
+	found := &providerPage{Results: []SearchResult{{ID: "W1", Title: "Gap Finding", Year: 2021}}, Total: 1}
+
+	tests := []struct {
+		name      string
+		providers []SearchProvider
+		wantIDs   []string
+		wantFail  []string
+		wantCache map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "unsupported provider is not a failure",
+			providers: []SearchProvider{
+				&stubProvider{name: "openalex", page: found},
+				&stubProvider{name: "crossref", err: errUnsupportedSearch},
+			},
+			wantIDs:   []string{"W1"},
+			wantCache: map[string]string{"openalex": db.CacheMiss},
+		},
+		{
+			name: "failed provider is listed",
+			providers: []SearchProvider{
+				&stubProvider{name: "openalex", page: found},
+				&stubProvider{name: "crossref", err: errUnsupportedSearch},
+				&stubProvider{name: "arxiv", err: errors.New("timeout")},
+			},
+			wantIDs:   []string{"W1"},
+			wantFail:  []string{"arxiv"},
+			wantCache: map[string]string{"openalex": db.CacheMiss},
+		},
+		{
+			name: "every provider unsupported",
+			providers: []SearchProvider{
+				&stubProvider{name: "crossref", err: errUnsupportedSearch},
+				&stubProvider{name: "arxiv", err: errUnsupportedSearch},
+			},
+			wantCache: map[string]string{},
+		},
+		{
+			name: "every supporting provider failed",
+			providers: []SearchProvider{
+				&stubProvider{name: "crossref", err: errUnsupportedSearch},
+				&stubProvider{name: "arxiv", err: errors.New("timeout")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{
+				config:    &config.Config{SearchProviderTimeout: time.Second},
+				providers: tt.providers,
+			}
+
+			page, err := h.federatedSearch(context.Background(), SearchRequest{Query: "gaps", Limit: 10, Page: 1})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("federatedSearch: %v", err)
+			}
+
+			var ids []string
+			for _, result := range page.Results {
+				ids = append(ids, result.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("results = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(page.FailedSources, tt.wantFail) {
+				t.Errorf("failed sources = %v, want %v", page.FailedSources, tt.wantFail)
+			}
+			if !reflect.DeepEqual(page.CacheStatus, tt.wantCache) {
+				t.Errorf("cache status = %v, want %v", page.CacheStatus, tt.wantCache)
+			}
+		})
+	}
+}
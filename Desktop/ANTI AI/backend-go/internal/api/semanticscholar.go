@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Semantic Scholar paging limits
+const (
+	semanticScholarMaxLimit = 100
+	// semanticScholarMaxResults is how far offset+limit may reach
+	semanticScholarMaxResults = 1000
+)
+
+// semanticScholarFields are the paper fields requested from Semantic Scholar
+const semanticScholarFields = "title,abstract,year,authors,externalIds,citationCount,isOpenAccess,openAccessPdf,url"
+
+// semanticScholarProvider searches the Semantic Scholar Graph API. It can
+// filter by year, open access, citations and journal articles.
+type semanticScholarProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// semanticScholarResponse represents the Semantic Scholar paper search response
+type semanticScholarResponse struct {
+	Total int `json:"total"`
+	Data  []struct {
+		PaperID       string `json:"paperId"`
+		Title         string `json:"title"`
+		Abstract      string `json:"abstract"`
+		Year          int    `json:"year"`
+		URL           string `json:"url"`
+		CitationCount int    `json:"citationCount"`
+		IsOpenAccess  bool   `json:"isOpenAccess"`
+		OpenAccessPDF *struct {
+			URL string `json:"url"`
+		} `json:"openAccessPdf"`
+		ExternalIDs struct {
+			DOI string `json:"DOI"`
+		} `json:"externalIds"`
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+	} `json:"data"`
+}
+
+// Name identifies Semantic Scholar in result sources
+func (p *semanticScholarProvider) Name() string { return "semanticscholar" }
+
+// Search fetches one page of papers from the Semantic Scholar API
+func (p *semanticScholarProvider) Search(ctx context.Context, search SearchRequest) (*providerPage, error) {
+	limit := search.Limit
+	if limit > semanticScholarMaxLimit {
+		limit = semanticScholarMaxLimit
+	}
+	offset := searchOffset(search)
+	if search.Cursor != "" || offset+limit > semanticScholarMaxResults {
+		return nil, errUnsupportedSearch
+	}
+
+	params := url.Values{}
+	params.Set("query", search.Query)
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("fields", semanticScholarFields)
+
+	if f := search.Filters; f != nil {
+		if usesOpenAlexIDs(f) {
+			return nil, errUnsupportedSearch
+		}
+		for _, t := range f.Types {
+			// Theses and preprints have no Semantic Scholar publication type
+			if !strings.EqualFold(strings.TrimSpace(t), "article") {
+				return nil, errUnsupportedSearch
+			}
+		}
+		if len(f.Types) > 0 {
+			params.Set("publicationTypes", "JournalArticle")
+		}
+
+		if f.YearFrom != 0 || f.YearTo != 0 {
+			years := ""
+			if f.YearFrom != 0 {
+				years = strconv.Itoa(f.YearFrom)
+			}
+			years += "-"
+			if f.YearTo != 0 {
+				years += strconv.Itoa(f.YearTo)
+			}
+			params.Set("year", years)
+		}
+		if f.OAOnly {
+			params.Set("openAccessPdf", "")
+		}
+		if f.MinCitations > 0 {
+			params.Set("minCitationCount", strconv.Itoa(f.MinCitations))
+		}
+	}
+
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("x-api-key", p.apiKey)
+	}
+
+	resp, err := providerGet(ctx, p.client, "Semantic Scholar", p.baseURL+"/paper/search?"+params.Encode(), header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var s2Resp semanticScholarResponse
+	if err := json.NewDecoder(resp.Body).Decode(&s2Resp); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, paper := range s2Resp.Data {
+		var authors []string
+		for _, author := range paper.Authors {
+			authors = append(authors, author.Name)
+		}
+
+		id := paper.URL
+		if id == "" {
+			id = fmt.Sprintf("https://www.semanticscholar.org/paper/%s", paper.PaperID)
+		}
+
+		result := SearchResult{
+			ID:           id,
+			Title:        paper.Title,
+			Authors:      authors,
+			Year:         paper.Year,
+			DOI:          doiURL(paper.ExternalIDs.DOI),
+			OA:           paper.IsOpenAccess,
+			PublisherURL: paper.URL,
+			Abstract:     paper.Abstract,
+			Citations:    paper.CitationCount,
+		}
+		if paper.OpenAccessPDF != nil && paper.OpenAccessPDF.URL != "" {
+			result.OA = true
+			result.PublisherURL = paper.OpenAccessPDF.URL
+		}
+
+		results = append(results, result)
+	}
+
+	return &providerPage{
+		Results: results,
+		Total:   s2Resp.Total,
+	}, nil
+}
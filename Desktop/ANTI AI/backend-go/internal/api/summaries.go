@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/jobs"
+	"gaply-backend/backend-go/internal/workerclient"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Summary variants understood by the worker
+var (
+	summaryScopes        = map[string]bool{"full": true, "abstract": true, "section": true}
+	summaryGranularities = map[string]bool{"sentence": true, "paragraph": true, "bullets": true}
+)
+
+// The variant mirrored onto papers.summary for the paper detail view
+const (
+	defaultSummaryScope       = "full"
+	defaultSummaryGranularity = "sentence"
+)
+
+// summaryProvenanceAttempts is how many summaries citing unknown chunks a job
+// accepts from the worker before giving up
+const summaryProvenanceAttempts = 2
+
+// SummaryRequest represents the summary request body
+type SummaryRequest struct {
+	Scope       string `json:"scope"`
+	Granularity string `json:"granularity"`
+	Force       bool   `json:"force"`
+}
+
+// SummaryResponse represents a stored summary variant
+type SummaryResponse struct {
+	ID          string                     `json:"id"`
+	PaperID     string                     `json:"paper_id"`
+	Scope       string                     `json:"scope"`
+	Granularity string                     `json:"granularity"`
+	Items       []workerclient.SummaryItem `json:"items"`
+	Cached      bool                       `json:"cached"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+}
+
+// RequestSummary handles POST /api/paper/:id/summaries
+// A stored variant is returned directly unless force is set; otherwise a
+// summarize job is queued, or the variant's live job joined, and its ID returned.
+func (h *Handlers) RequestSummary(c *fiber.Ctx) error {
+	var req SummaryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Scope == "" {
+		req.Scope = defaultSummaryScope
+	}
+	if req.Granularity == "" {
+		req.Granularity = defaultSummaryGranularity
+	}
+	if err := validateSummaryVariant(req.Scope, req.Granularity); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	if paper.IngestStatus != StatusCompleted {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error":         "Paper must be ingested before it can be summarized",
+			"ingest_status": paper.IngestStatus,
+		})
+	}
+
+	if !req.Force {
+		summary, err := h.models.GetSummary(c.Context(), paper.ID, req.Scope, req.Granularity)
+		if err == nil {
+			response := summaryResponse(summary)
+			response.Cached = true
+			return c.JSON(response)
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load summary",
+			})
+		}
+	}
+
+	// Concurrent requests for the same variant share one summarize job
+	key := paper.ID.String() + "/" + req.Scope + "/" + req.Granularity
+	return h.enqueueUnique(c, jobs.TypeSummarize, key, workerclient.SummarizeRequest{
+		PaperID:     paper.ID.String(),
+		Scope:       req.Scope,
+		Granularity: req.Granularity,
+	})
+}
+
+// ListSummaries handles GET /api/paper/:id/summaries
+func (h *Handlers) ListSummaries(c *fiber.Ctx) error {
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	summaries, err := h.models.ListSummaries(c.Context(), paper.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load summaries",
+		})
+	}
+
+	response := []SummaryResponse{}
+	for i := range summaries {
+		response = append(response, summaryResponse(&summaries[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"paper_id":  paper.ID.String(),
+		"summaries": response,
+	})
+}
+
+// GetSummary handles GET /api/paper/:id/summaries/:scope/:granularity
+func (h *Handlers) GetSummary(c *fiber.Ctx) error {
+	scope, granularity := c.Params("scope"), c.Params("granularity")
+	if err := validateSummaryVariant(scope, granularity); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	paper, err := h.libraryPaper(c)
+	if err != nil {
+		return paperLookupError(c, err)
+	}
+
+	summary, err := h.models.GetSummary(c.Context(), paper.ID, scope, granularity)
+	if errors.Is(err, db.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Summary not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load summary",
+		})
+	}
+
+	return c.JSON(summaryResponse(summary))
+}
+
+// processSummarize runs a queued summarize job and stores the result
+func (h *Handlers) processSummarize(ctx context.Context, task *jobs.Task) (interface{}, error) {
+	var req workerclient.SummarizeRequest
+	if err := task.Decode(&req); err != nil {
+		return nil, err
+	}
+
+	paperID, err := uuid.Parse(req.PaperID)
+	if err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid paper ID %q", req.PaperID))
+	}
+	if req.Scope == "" {
+		req.Scope = defaultSummaryScope
+	}
+	if req.Granularity == "" {
+		req.Granularity = defaultSummaryGranularity
+	}
+	if err := validateSummaryVariant(req.Scope, req.Granularity); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	resp, err := h.worker.SummarizePaper(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := json.Marshal(resp.Summary)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	var cited []string
+	seen := make(map[string]bool)
+	for _, item := range resp.Summary {
+		for _, p := range item.Provenance {
+			if !seen[p.ChunkID] {
+				seen[p.ChunkID] = true
+				cited = append(cited, p.ChunkID)
+			}
+		}
+	}
+
+	// A summary citing unknown chunks is rejected. The worker is asked once
+	// more, as it may cite correctly next time; after that the job is
+	// dead-lettered with the unknown chunk IDs as its error.
+	summary := &db.Summary{
+		PaperID:     paperID,
+		Scope:       req.Scope,
+		Granularity: req.Granularity,
+		Items:       items,
+	}
+	if err := h.models.UpsertSummary(ctx, summary, cited); err != nil {
+		var missing *db.MissingChunksError
+		if errors.As(err, &missing) && (task.Attempts >= summaryProvenanceAttempts || task.LastAttempt()) {
+			return nil, jobs.Permanent(err)
+		}
+		return nil, err
+	}
+
+	if req.Scope == defaultSummaryScope && req.Granularity == defaultSummaryGranularity {
+		if err := h.models.UpdatePaperSummary(ctx, paperID, items); err != nil {
+			return nil, err
+		}
+	}
+
+	return summaryResponse(summary), nil
+}
+
+// validateSummaryVariant checks a scope and granularity against what the worker supports
+func validateSummaryVariant(scope, granularity string) error {
+	if !summaryScopes[scope] {
+		return fmt.Errorf("unknown summary scope %q (expected full, abstract or section)", scope)
+	}
+	if !summaryGranularities[granularity] {
+		return fmt.Errorf("unknown summary granularity %q (expected sentence, paragraph or bullets)", granularity)
+	}
+	return nil
+}
+
+// summaryResponse converts a stored summary into its response form
+func summaryResponse(s *db.Summary) SummaryResponse {
+	items := []workerclient.SummaryItem{}
+	_ = json.Unmarshal(s.Items, &items)
+
+	return SummaryResponse{
+		ID:          s.ID.String(),
+		PaperID:     s.PaperID.String(),
+		Scope:       s.Scope,
+		Granularity: s.Granularity,
+		Items:       items,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/auth"
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// uploadURLTTL bounds how long a signed upload URL stays usable
+const uploadURLTTL = 2 * time.Hour
+
+// errUploadInUse is returned when finalizing would move or delete an object
+// that a paper refers to
+var errUploadInUse = errors.New("storage object is in use by a paper")
+
+// pdfMagic is the signature every PDF file starts with
+var pdfMagic = []byte("%PDF-")
+
+// UploadURLRequest represents the upload URL request body. SHA256 is the
+// client's hex digest of the PDF; with it the upload goes straight to its
+// content-addressed path and PDFs a paper already has are reported.
+type UploadURLRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+}
+
+// UploadURLResponse is returned by POST /api/upload-url. When Exists is set the
+// PDF is already stored. With PaperID it belongs to that paper, which joins the
+// caller's library only once they upload their copy to UploadURL and finalize
+// it; without, it is stored at Path and can be finalized without uploading.
+type UploadURLResponse struct {
+	UploadURL    string     `json:"upload_url,omitempty"`
+	Path         string     `json:"path"`
+	Token        string     `json:"token,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Exists       bool       `json:"exists"`
+	PaperID      string     `json:"paper_id,omitempty"`
+	IngestStatus string     `json:"ingest_status,omitempty"`
+}
+
+// FinalizeUploadRequest represents the finalize request body. The object at
+// Path is attached to PaperID, to the paper with DOI, to the paper already
+// holding identical content, or to a new paper. SHA256, when given, must match
+// the uploaded content.
+type FinalizeUploadRequest struct {
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256,omitempty"`
+	PaperID string `json:"paper_id,omitempty"`
+	DOI     string `json:"doi,omitempty"`
+	Ingest  bool   `json:"ingest,omitempty"`
+}
+
+// FinalizeUploadResponse is returned when an upload is recorded without ingest
+type FinalizeUploadResponse struct {
+	PaperID        string `json:"paper_id"`
+	StoragePath    string `json:"storage_path"`
+	SHA256         string `json:"sha256"`
+	IngestStatus   string `json:"ingest_status"`
+	Deduplicated   bool   `json:"deduplicated"`
+	ContentChanged bool   `json:"content_changed"`
+}
+
+// GetUploadURL handles POST /api/upload-url
+// Returns a signed URL the client uploads the PDF to, and the object path to
+// pass to POST /api/upload/finalize afterwards. The path is recorded as issued
+// to the caller, and only the caller can finalize it.
+func (h *Handlers) GetUploadURL(c *fiber.Ctx) error {
+	userID := auth.GetUserID(c)
+	if userID == "" {
+		return editorError(c, errNoEditor)
+	}
+
+	var req UploadURLRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Filename == "" {
+		req.Filename = "paper.pdf"
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/pdf"
+	}
+	if !strings.EqualFold(path.Ext(req.Filename), ".pdf") || req.ContentType != "application/pdf" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only PDF uploads are accepted",
+		})
+	}
+
+	ctx := c.Context()
+
+	objectPath := storage.NewObjectPath(req.Filename)
+	var existing *db.Paper
+	if req.SHA256 != "" {
+		req.SHA256 = strings.ToLower(req.SHA256)
+		if !storage.ValidSHA256(req.SHA256) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "'sha256' must be a hex SHA-256 digest",
+			})
+		}
+
+		paper, err := h.models.GetPaperByContentHash(ctx, req.SHA256)
+		switch {
+		case err == nil:
+			// A known digest proves nothing, so the caller still uploads their
+			// copy to a fresh path; finalizing verifies it and drops the duplicate
+			existing = paper
+		case !errors.Is(err, db.ErrNotFound):
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to look up existing uploads",
+			})
+		default:
+			objectPath = storage.ContentPath(req.SHA256)
+
+			// Stored but never finalized; the client can finalize without uploading
+			exists, err := h.storage.FileExists(ctx, objectPath)
+			if err != nil {
+				return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+					"error": "Failed to check storage",
+				})
+			}
+			if exists {
+				if err := h.models.RecordUpload(ctx, objectPath, userID, time.Now().Add(h.config.UploadFinalizeWindow)); err != nil {
+					return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+						"error": "Failed to record upload",
+					})
+				}
+				return c.JSON(UploadURLResponse{Path: objectPath, Exists: true})
+			}
+		}
+	}
+
+	upload, err := h.storage.GetSignedURL(ctx, objectPath, req.ContentType, uploadURLTTL)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to create upload URL",
+		})
+	}
+
+	if err := h.models.RecordUpload(ctx, upload.Path, userID, time.Now().Add(h.config.UploadFinalizeWindow)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record upload",
+		})
+	}
+
+	response := UploadURLResponse{
+		UploadURL: upload.URL,
+		Path:      upload.Path,
+		Token:     upload.Token,
+		ExpiresAt: &upload.ExpiresAt,
+	}
+	if existing != nil {
+		response.Exists = true
+		response.PaperID = existing.ID.String()
+		response.IngestStatus = existing.IngestStatus
+	}
+	return c.JSON(response)
+}
+
+// FinalizeUpload handles POST /api/upload/finalize
+// Checks that the uploaded object exists and is a PDF, moves it to its
+// content-addressed path, records it on a paper and, when requested, queues
+// ingest straight away. Content already ingested is not ingested again.
+// Only paths /api/upload-url issued to the caller are accepted, and a PDF can
+// only be attached to a paper in the caller's library.
+func (h *Handlers) FinalizeUpload(c *fiber.Ctx) error {
+	userID := auth.GetUserID(c)
+	if userID == "" {
+		return editorError(c, errNoEditor)
+	}
+
+	var req FinalizeUploadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Path = strings.TrimSpace(req.Path)
+	if !storage.IsUploadPath(req.Path) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "'path' must be the storage path returned by /api/upload-url",
+		})
+	}
+	req.DOI = normalizeDOI(req.DOI)
+	req.SHA256 = strings.ToLower(req.SHA256)
+
+	ctx := c.Context()
+
+	issued, err := h.models.UploadIssued(ctx, req.Path, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to look up upload",
+		})
+	}
+	if !issued {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "No upload was issued to you for this path, or it has expired",
+		})
+	}
+
+	// Check the target paper before touching storage
+	var paper *db.Paper
+	if req.PaperID != "" {
+		id, err := uuid.Parse(req.PaperID)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "'paper_id' must be a paper UUID",
+			})
+		}
+		paper, err = h.models.GetPaperByID(ctx, id)
+		if err != nil {
+			return paperLookupError(c, err)
+		}
+		allowed, err := h.canAccessPaper(c, paper.ID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check library",
+			})
+		}
+		if !allowed {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{
+				"error": "Only papers in your library can be given a new PDF",
+			})
+		}
+	}
+
+	head, err := h.storage.ReadHead(ctx, req.Path, len(pdfMagic))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Uploaded file not found; upload it before finalizing",
+			})
+		}
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+	if !bytes.Equal(head, pdfMagic) {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Uploaded file is not a PDF",
+		})
+	}
+
+	hash, err := storage.HashObject(ctx, h.storage, req.Path)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+	if req.SHA256 != "" && req.SHA256 != hash {
+		// A wrong file at a content path would poison every later dedup hit
+		if req.Path == storage.ContentPath(req.SHA256) {
+			h.deleteUnreferenced(ctx, req.Path)
+		}
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "Uploaded file does not match 'sha256'",
+			"sha256": hash,
+		})
+	}
+
+	storagePath, err := h.storeContent(ctx, req.Path, hash)
+	if errors.Is(err, errUploadInUse) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "This path holds a paper's PDF and cannot be finalized",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to store uploaded file",
+		})
+	}
+
+	deduplicated := false
+	if paper == nil {
+		existing, err := h.models.GetPaperByContentHash(ctx, hash)
+		switch {
+		case err == nil && (req.DOI == "" || req.DOI == existing.DOI):
+			paper, deduplicated = existing, true
+		case err != nil && !errors.Is(err, db.ErrNotFound):
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to look up existing uploads",
+			})
+		default:
+			paper, err = h.findOrCreateIngestPaper(ctx, IngestRequest{DOI: req.DOI, StoragePath: storagePath})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to create paper record",
+				})
+			}
+		}
+
+		// A DOI can name an existing paper with a PDF of its own, which only
+		// the same people who could name it by paper_id may replace
+		if !deduplicated && paper.StoragePath != nil && *paper.StoragePath != storagePath {
+			allowed, err := h.canAccessPaper(c, paper.ID)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to check library",
+				})
+			}
+			if !allowed {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{
+					"error": "Only papers in your library can be given a new PDF",
+				})
+			}
+		}
+	}
+
+	changed, err := h.models.SetPaperContent(ctx, paper.ID, storagePath, hash)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record upload on paper",
+		})
+	}
+	paper.StoragePath = &storagePath
+	paper.ContentHash = &hash
+	h.addToLibrary(c, paper.ID)
+	if err := h.models.ConsumeUpload(ctx, req.Path, userID); err != nil {
+		log.Printf("finalize: failed to clear upload record %s: %v", req.Path, err)
+	}
+
+	if req.Ingest {
+		// New content for an ingested paper has to be ingested again
+		if changed && paper.IngestStatus == StatusCompleted {
+			paper.IngestStatus = StatusPending
+		}
+		return h.startIngest(c, paper, IngestPayload{
+			PaperID:     paper.ID,
+			DOI:         paper.DOI,
+			StoragePath: storagePath,
+		})
+	}
+
+	return c.JSON(FinalizeUploadResponse{
+		PaperID:        paper.ID.String(),
+		StoragePath:    storagePath,
+		SHA256:         hash,
+		IngestStatus:   paper.IngestStatus,
+		Deduplicated:   deduplicated,
+		ContentChanged: changed,
+	})
+}
+
+// storeContent moves an upload to its content-addressed path, dropping it
+// instead when identical content is already stored there. Objects a paper
+// refers to are never moved or dropped.
+func (h *Handlers) storeContent(ctx context.Context, uploadPath, hash string) (string, error) {
+	contentPath := storage.ContentPath(hash)
+	if uploadPath == contentPath {
+		return contentPath, nil
+	}
+
+	referenced, err := h.models.ReferencedStoragePaths(ctx, []string{uploadPath})
+	if err != nil {
+		return "", err
+	}
+	if referenced[uploadPath] {
+		return "", errUploadInUse
+	}
+
+	exists, err := h.storage.FileExists(ctx, contentPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		if err := h.storage.DeleteFile(ctx, uploadPath); err != nil {
+			log.Printf("finalize: failed to delete duplicate upload %s: %v", uploadPath, err)
+		}
+		return contentPath, nil
+	}
+
+	if err := h.storage.MoveFile(ctx, uploadPath, contentPath); err != nil {
+		return "", err
+	}
+	return contentPath, nil
+}
+
+// deleteUnreferenced deletes an object unless a paper refers to it, logging
+// rather than returning failures
+func (h *Handlers) deleteUnreferenced(ctx context.Context, objectPath string) {
+	referenced, err := h.models.ReferencedStoragePaths(ctx, []string{objectPath})
+	if err == nil && referenced[objectPath] {
+		return
+	}
+	if err == nil {
+		err = h.storage.DeleteFile(ctx, objectPath)
+	}
+	if err != nil {
+		log.Printf("finalize: failed to delete mismatched upload %s: %v", objectPath, err)
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrInvalidSignature is returned when a callback token does not verify
+var ErrInvalidSignature = errors.New("invalid callback signature")
+
+// ErrCallbackExpired is returned when a callback token is past its expiry
+var ErrCallbackExpired = errors.New("callback token expired")
+
+// SignCallback returns an HMAC token binding a job ID to an expiry time
+func SignCallback(secret, jobID string, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%d", jobID, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallback checks a callback token against the job ID and expiry it was signed for
+func VerifyCallback(secret, jobID string, expires int64, token string) error {
+	expected := SignCallback(secret, jobID, time.Unix(expires, 0))
+	if !hmac.Equal([]byte(expected), []byte(token)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrCallbackExpired
+	}
+	return nil
+}
+
+// CallbackMiddleware authenticates worker callbacks signed with SignCallback.
+// The job ID comes from the :jobId route parameter, the expiry and token from the query string.
+func CallbackMiddleware(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing or invalid callback expiry",
+			})
+		}
+
+		if err := VerifyCallback(secret, c.Params("jobId"), expires, c.Query("token")); err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid callback token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
@@ -16,20 +20,61 @@ type Config struct {
 	DatabaseURL string
 
 	// Supabase configuration
-	SupabaseURL         string
-	SupabaseServiceKey  string
-	SupabaseAnonKey     string
-	JWTSecret           string
+	SupabaseURL        string
+	SupabaseServiceKey string
+	SupabaseAnonKey    string
+	JWTSecret          string
+
+	// Storage configuration
+	StorageBackend       string
+	LocalStorageDir      string
+	StorageSigningSecret string
+	MaxUploadBytes       int
+	// MaxRequestBytes caps request bodies on every route but direct uploads
+	MaxRequestBytes int
+	// UploadFinalizeWindow bounds how long after it was issued an upload path
+	// can be finalized
+	UploadFinalizeWindow time.Duration
 
 	// Worker configuration
-	WorkerURL string
+	WorkerURL             string
+	PublicURL             string
+	WorkerCallbackSecret  string
+	WorkerCallbackTimeout time.Duration
 
 	// External services
-	OpenAlexBaseURL string
-	UnpaywallEmail  string
-	GROBIDURL       string
-	ChromaURL       string
-	LanguageToolURL string
+	OpenAlexBaseURL        string
+	CrossrefBaseURL        string
+	ArxivBaseURL           string
+	SemanticScholarBaseURL string
+	SemanticScholarAPIKey  string
+	UnpaywallEmail         string
+	GROBIDURL              string
+	ChromaURL              string
+	LanguageToolURL        string
+
+	// Search providers, queried in this order, each limited to SearchProviderTimeout
+	SearchProviders       []string
+	SearchProviderTimeout time.Duration
+
+	// External API response cache. Each source's responses are fresh for its
+	// TTL, then served for up to CacheStaleFor more while being refreshed.
+	OpenAlexCacheTTL        time.Duration
+	CrossrefCacheTTL        time.Duration
+	ArxivCacheTTL           time.Duration
+	SemanticScholarCacheTTL time.Duration
+	UnpaywallCacheTTL       time.Duration
+	CacheStaleFor           time.Duration
+
+	// Search enrichment and ranking
+	UnpaywallConcurrency int
+	UnpaywallDeadline    time.Duration
+	RankWeights          RankWeights
+
+	// Query spelling suggestions
+	SpellingRefreshInterval time.Duration
+	SpellingMinWordCount    int
+	SpellingMaxWords        int
 
 	// Redis configuration (optional)
 	RedisURL string
@@ -44,6 +89,32 @@ type Config struct {
 
 	// Rate limiting
 	RateLimitPerMinute int
+
+	// Job queue
+	JobLease                   time.Duration
+	JobPollInterval            time.Duration
+	JobMaxAttempts             int
+	JobBackoffBase             time.Duration
+	JobBackoffMax              time.Duration
+	JobConcurrencyIngest       int
+	JobConcurrencySummarize    int
+	JobConcurrencyGapFind      int
+	JobConcurrencyJournalCheck int
+
+	// Storage and paper reconciler
+	ReconcileInterval    time.Duration
+	ReconcileOrphanGrace time.Duration
+	ReconcileStuckAfter  time.Duration
+	ReconcileDryRun      bool
+}
+
+// RankWeights weighs the components of a search result's relevance score
+type RankWeights struct {
+	Text       float64
+	Recency    float64
+	Citations  float64
+	OpenAccess float64
+	Ingested   float64
 }
 
 // Load loads configuration from environment variables
@@ -56,7 +127,14 @@ func Load() (*Config, error) {
 		SupabaseAnonKey:    getEnv("SUPABASE_ANON_KEY", ""),
 		JWTSecret:          getEnv("JWT_SECRET", ""),
 		WorkerURL:          getEnv("WORKER_URL", "http://localhost:8000"),
+		PublicURL:          getEnv("API_PUBLIC_URL", ""),
+		StorageBackend:     strings.ToLower(getEnv("STORAGE_BACKEND", "supabase")),
+		LocalStorageDir:    getEnv("LOCAL_STORAGE_DIR", "./data/storage"),
+		MaxUploadBytes:     getEnvInt("MAX_UPLOAD_BYTES", 50*1024*1024),
+		MaxRequestBytes:    getEnvInt("MAX_REQUEST_BYTES", 1024*1024),
 		OpenAlexBaseURL:    getEnv("OPENALEX_BASE_URL", "https://api.openalex.org"),
+		CrossrefBaseURL:    getEnv("CROSSREF_BASE_URL", "https://api.crossref.org"),
+		ArxivBaseURL:       getEnv("ARXIV_BASE_URL", "http://export.arxiv.org/api"),
 		UnpaywallEmail:     getEnv("UNPAYWALL_EMAIL", ""),
 		GROBIDURL:          getEnv("GROBID_URL", "http://localhost:8070"),
 		ChromaURL:          getEnv("CHROMA_URL", "http://localhost:8000"),
@@ -66,6 +144,51 @@ func Load() (*Config, error) {
 		EnableLocalLLM:     getEnvBool("ENABLE_LOCAL_LLM", false),
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
 		RateLimitPerMinute: getEnvInt("RATE_LIMIT_PER_MINUTE", 100),
+
+		WorkerCallbackSecret:  getEnv("WORKER_CALLBACK_SECRET", ""),
+		WorkerCallbackTimeout: getEnvDuration("WORKER_CALLBACK_TIMEOUT", 30*time.Minute),
+
+		JobLease:                   getEnvDuration("JOB_LEASE", 2*time.Minute),
+		JobPollInterval:            getEnvDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		JobMaxAttempts:             getEnvInt("JOB_MAX_ATTEMPTS", 5),
+		JobBackoffBase:             getEnvDuration("JOB_BACKOFF_BASE", 10*time.Second),
+		JobBackoffMax:              getEnvDuration("JOB_BACKOFF_MAX", 30*time.Minute),
+		JobConcurrencyIngest:       getEnvInt("JOB_CONCURRENCY_INGEST", 2),
+		JobConcurrencySummarize:    getEnvInt("JOB_CONCURRENCY_SUMMARIZE", 2),
+		JobConcurrencyGapFind:      getEnvInt("JOB_CONCURRENCY_GAPFIND", 1),
+		JobConcurrencyJournalCheck: getEnvInt("JOB_CONCURRENCY_JOURNAL_CHECK", 2),
+
+		SemanticScholarBaseURL: getEnv("SEMANTIC_SCHOLAR_BASE_URL", "https://api.semanticscholar.org/graph/v1"),
+		SemanticScholarAPIKey:  getEnv("SEMANTIC_SCHOLAR_API_KEY", ""),
+		SearchProviderTimeout:  getEnvDuration("SEARCH_PROVIDER_TIMEOUT", 8*time.Second),
+
+		OpenAlexCacheTTL:        getEnvDuration("OPENALEX_CACHE_TTL", 24*time.Hour),
+		CrossrefCacheTTL:        getEnvDuration("CROSSREF_CACHE_TTL", 24*time.Hour),
+		ArxivCacheTTL:           getEnvDuration("ARXIV_CACHE_TTL", 6*time.Hour),
+		SemanticScholarCacheTTL: getEnvDuration("SEMANTIC_SCHOLAR_CACHE_TTL", 24*time.Hour),
+		UnpaywallCacheTTL:       getEnvDuration("UNPAYWALL_CACHE_TTL", 7*24*time.Hour),
+		CacheStaleFor:           getEnvDuration("API_CACHE_STALE_FOR", 24*time.Hour),
+
+		UnpaywallConcurrency: getEnvInt("UNPAYWALL_CONCURRENCY", 8),
+		UnpaywallDeadline:    getEnvDuration("UNPAYWALL_DEADLINE", 5*time.Second),
+		RankWeights: RankWeights{
+			Text:       getEnvFloat("RANK_WEIGHT_TEXT", 0.55),
+			Recency:    getEnvFloat("RANK_WEIGHT_RECENCY", 0.15),
+			Citations:  getEnvFloat("RANK_WEIGHT_CITATIONS", 0.15),
+			OpenAccess: getEnvFloat("RANK_WEIGHT_OA", 0.05),
+			Ingested:   getEnvFloat("RANK_WEIGHT_INGESTED", 0.10),
+		},
+
+		SpellingRefreshInterval: getEnvDuration("SPELLING_REFRESH_INTERVAL", time.Hour),
+		SpellingMinWordCount:    getEnvInt("SPELLING_MIN_WORD_COUNT", 3),
+		SpellingMaxWords:        getEnvInt("SPELLING_MAX_WORDS", 100000),
+
+		ReconcileInterval:    getEnvDuration("RECONCILE_INTERVAL", time.Hour),
+		ReconcileOrphanGrace: getEnvDuration("RECONCILE_ORPHAN_GRACE", 24*time.Hour),
+		ReconcileStuckAfter:  getEnvDuration("RECONCILE_STUCK_AFTER", 6*time.Hour),
+		ReconcileDryRun:      getEnvBool("RECONCILE_DRY_RUN", true),
+
+		UploadFinalizeWindow: getEnvDuration("UPLOAD_FINALIZE_WINDOW", 24*time.Hour),
 	}
 
 	// Parse allowed origins
@@ -76,6 +199,32 @@ func Load() (*Config, error) {
 		cfg.AllowedOrigins = []string{cfg.FrontendURL}
 	}
 
+	// Parse search providers
+	for _, name := range strings.Split(getEnv("SEARCH_PROVIDERS", "openalex,crossref,arxiv,semanticscholar"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			cfg.SearchProviders = append(cfg.SearchProviders, name)
+		}
+	}
+
+	// The worker reaches the API on its own port unless a public URL is given
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = "http://localhost:" + cfg.Port
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+
+	// Callbacks and local storage URLs are signed with keys derived from the JWT
+	// secret unless dedicated ones are set, so a leaked signature key cannot
+	// forge user tokens or the other kind of signature
+	cfg.StorageSigningSecret = getEnv("STORAGE_SIGNING_SECRET", "")
+	if cfg.JWTSecret != "" {
+		if cfg.WorkerCallbackSecret == "" {
+			cfg.WorkerCallbackSecret = deriveSecret(cfg.JWTSecret, "callback")
+		}
+		if cfg.StorageSigningSecret == "" {
+			cfg.StorageSigningSecret = deriveSecret(cfg.JWTSecret, "storage")
+		}
+	}
+
 	// Validate required configuration
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
@@ -86,21 +235,60 @@ func Load() (*Config, error) {
 
 // validate ensures all required configuration is present
 func (c *Config) validate() error {
-	if c.SupabaseURL == "" {
-		return fmt.Errorf("SUPABASE_URL is required")
+	switch c.StorageBackend {
+	case "supabase":
+		if c.SupabaseURL == "" {
+			return fmt.Errorf("SUPABASE_URL is required")
+		}
+		if c.SupabaseServiceKey == "" {
+			return fmt.Errorf("SUPABASE_SERVICE_ROLE_KEY is required")
+		}
+	case "local":
+		if c.LocalStorageDir == "" {
+			return fmt.Errorf("LOCAL_STORAGE_DIR is required for local storage")
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be 'supabase' or 'local', got %q", c.StorageBackend)
+	}
+	if len(c.SearchProviders) == 0 {
+		return fmt.Errorf("SEARCH_PROVIDERS must name at least one provider")
 	}
-	if c.SupabaseServiceKey == "" {
-		return fmt.Errorf("SUPABASE_SERVICE_ROLE_KEY is required")
+	for _, name := range c.SearchProviders {
+		switch name {
+		case "openalex", "crossref", "arxiv", "semanticscholar":
+		default:
+			return fmt.Errorf("unknown search provider %q in SEARCH_PROVIDERS", name)
+		}
 	}
 	if c.JWTSecret == "" {
 		return fmt.Errorf("JWT_SECRET is required")
 	}
+	if c.WorkerCallbackSecret == c.JWTSecret || c.StorageSigningSecret == c.JWTSecret {
+		return fmt.Errorf("WORKER_CALLBACK_SECRET and STORAGE_SIGNING_SECRET must differ from JWT_SECRET")
+	}
+	if c.WorkerCallbackSecret == c.StorageSigningSecret {
+		return fmt.Errorf("WORKER_CALLBACK_SECRET and STORAGE_SIGNING_SECRET must differ")
+	}
 	if c.DatabaseURL == "" {
 		return fmt.Errorf("DB_URL is required")
 	}
+	if c.UploadFinalizeWindow <= 0 {
+		return fmt.Errorf("UPLOAD_FINALIZE_WINDOW must be positive")
+	}
+	// An upload that can still be finalized must not be collected as an orphan
+	if c.ReconcileOrphanGrace < c.UploadFinalizeWindow {
+		return fmt.Errorf("RECONCILE_ORPHAN_GRACE (%s) must be at least UPLOAD_FINALIZE_WINDOW (%s)", c.ReconcileOrphanGrace, c.UploadFinalizeWindow)
+	}
 	return nil
 }
 
+// deriveSecret derives a key for one purpose from a root secret
+func deriveSecret(root, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(root))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // getEnv gets an environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -128,3 +316,23 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvFloat gets a floating point environment variable
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable such as "30s" or "5m"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
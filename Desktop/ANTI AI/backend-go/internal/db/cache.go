@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache statuses reported by GetOrFetch
+const (
+	// CacheHit means a fresh entry was served
+	CacheHit = "hit"
+	// CacheStale means an entry past its TTL was served while it is refreshed
+	CacheStale = "stale"
+	// CacheMiss means the value was fetched upstream
+	CacheMiss = "miss"
+)
+
+// SearchCachePrefix starts the cache keys of search provider pages. Those
+// entries hold their works under "results", each with a "title", which the
+// spelling vocabulary reads.
+const SearchCachePrefix = "search:"
+
+// Bounds on upstream fetches made on behalf of more than one caller
+const (
+	// sharedFetchTimeout bounds a fetch that concurrent misses wait on
+	sharedFetchTimeout = 30 * time.Second
+	// staleRefreshTimeout bounds a background refresh of a stale entry
+	staleRefreshTimeout = 30 * time.Second
+)
+
+// CachePolicy sets how long a source's responses are fresh, and how much
+// longer after that they may still be served while being refreshed
+type CachePolicy struct {
+	TTL      time.Duration
+	StaleFor time.Duration
+}
+
+// CacheEntry is a cached response and whether it has outlived its TTL
+type CacheEntry struct {
+	Data  json.RawMessage
+	Stale bool
+}
+
+// FetchFunc fetches a value from upstream for the cache
+type FetchFunc func(ctx context.Context) (json.RawMessage, error)
+
+// Cache is a read-through TTL cache over the api_cache table. Concurrent
+// fetches of one key share a single upstream call, and stale entries are
+// served immediately while one background refresh replaces them. Errors are
+// never cached.
+type Cache struct {
+	models *Models
+	group  singleflight.Group
+	wg     sync.WaitGroup
+}
+
+// NewCache creates a cache backed by api_cache
+func NewCache(models *Models) *Cache {
+	return &Cache{models: models}
+}
+
+// GetOrFetch returns the value cached under key, calling fetch when there is
+// none, and reports whether it was a hit, stale or a miss. A cache that
+// cannot be read is treated as a miss.
+func (c *Cache) GetOrFetch(ctx context.Context, key string, policy CachePolicy, fetch FetchFunc) (json.RawMessage, string, error) {
+	entry, err := c.models.GetCacheEntry(ctx, key)
+	switch {
+	case err == nil && !entry.Stale:
+		return entry.Data, CacheHit, nil
+	case err == nil:
+		c.refresh(key, policy, fetch)
+		return entry.Data, CacheStale, nil
+	case !errors.Is(err, ErrNotFound):
+		log.Printf("cache: failed to read %s: %v", key, err)
+	}
+
+	// The fetch is shared, so it must outlive whichever caller started it;
+	// each caller still waits only as long as its own context allows
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedFetchTimeout)
+		defer cancel()
+		return c.fetchAndStore(ctx, key, policy, fetch)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, "", res.Err
+		}
+		return res.Val.(json.RawMessage), CacheMiss, nil
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
+// Wait blocks until background refreshes have finished
+func (c *Cache) Wait() {
+	c.wg.Wait()
+}
+
+// refresh fetches a stale key again in the background, unless a fetch of it
+// is already running
+func (c *Cache) refresh(key string, policy CachePolicy, fetch FetchFunc) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), staleRefreshTimeout)
+		defer cancel()
+
+		_, err, _ := c.group.Do(key, func() (interface{}, error) {
+			return c.fetchAndStore(ctx, key, policy, fetch)
+		})
+		if err != nil {
+			log.Printf("cache: failed to refresh %s: %v", key, err)
+		}
+	}()
+}
+
+// fetchAndStore fetches a value and caches it. A failed write is logged, as
+// the caller still has its value.
+func (c *Cache) fetchAndStore(ctx context.Context, key string, policy CachePolicy, fetch FetchFunc) (json.RawMessage, error) {
+	data, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.models.SetCache(ctx, key, data, policy); err != nil {
+		log.Printf("cache: failed to store %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// GetCacheEntry returns the cached response for key if it may still be served
+func (m *Models) GetCacheEntry(ctx context.Context, key string) (*CacheEntry, error) {
+	var entry CacheEntry
+	query := `SELECT response_data, stale_at <= NOW() FROM api_cache
+		WHERE cache_key = $1 AND expires_at > NOW()`
+	err := m.conn.GetPool().QueryRow(ctx, query, key).Scan(&entry.Data, &entry.Stale)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &entry, nil
+}
+
+// SetCache stores a response under key for the policy's TTL plus stale
+// window, replacing any previous entry
+func (m *Models) SetCache(ctx context.Context, key string, data json.RawMessage, policy CachePolicy) error {
+	query := `
+		INSERT INTO api_cache (cache_key, response_data, stale_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (cache_key) DO UPDATE
+		SET response_data = EXCLUDED.response_data,
+		    stale_at = EXCLUDED.stale_at,
+		    expires_at = EXCLUDED.expires_at,
+		    created_at = EXCLUDED.created_at
+	`
+	now := time.Now()
+	staleAt := now.Add(policy.TTL)
+	_, err := m.conn.GetPool().Exec(ctx, query, key, data, staleAt, staleAt.Add(policy.StaleFor))
+	return err
+}
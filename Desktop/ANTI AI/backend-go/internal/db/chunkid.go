@@ -0,0 +1,37 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// chunkIDPattern mirrors the chunk_id_format check constraint: doi::p{page}::para{n}::s{m}
+var chunkIDPattern = regexp.MustCompile(`^(.+)::p(\d+)::para(\d+)::s(\d+)$`)
+
+// ChunkLocation is the position a chunk ID encodes
+type ChunkLocation struct {
+	DOI            string `json:"doi"`
+	Page           int    `json:"page"`
+	ParagraphIndex int    `json:"paragraph_index"`
+	SentenceIndex  int    `json:"sentence_index"`
+}
+
+// ParseChunkID splits a chunk ID into its DOI and page/paragraph/sentence indexes
+func ParseChunkID(id string) (ChunkLocation, error) {
+	m := chunkIDPattern.FindStringSubmatch(id)
+	if m == nil {
+		return ChunkLocation{}, fmt.Errorf("chunk id %q does not match doi::p{page}::para{n}::s{m}", id)
+	}
+
+	page, _ := strconv.Atoi(m[2])
+	para, _ := strconv.Atoi(m[3])
+	sent, _ := strconv.Atoi(m[4])
+
+	return ChunkLocation{DOI: m[1], Page: page, ParagraphIndex: para, SentenceIndex: sent}, nil
+}
+
+// FormatChunkID builds the chunk ID for a location
+func FormatChunkID(loc ChunkLocation) string {
+	return fmt.Sprintf("%s::p%d::para%d::s%d", loc.DOI, loc.Page, loc.ParagraphIndex, loc.SentenceIndex)
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// EditLocation is stored in edits.location and pins an edit to a chunk
+type EditLocation struct {
+	ChunkID string `json:"chunk_id"`
+	ChunkLocation
+}
+
+// EditConflictError is returned when an edit was prepared against chunk text
+// that has since changed, either because old_text no longer matches or the
+// caller's base version is stale
+type EditConflictError struct {
+	ChunkID        string
+	CurrentText    string
+	CurrentVersion int
+	// EditID is the edit being reverted when the conflict came from a revert
+	EditID *uuid.UUID
+}
+
+func (e *EditConflictError) Error() string {
+	return fmt.Sprintf("chunk %s has changed since the edit was prepared (now version %d)", e.ChunkID, e.CurrentVersion)
+}
+
+// editColumns lists the edits columns in the order scanEdit expects
+const editColumns = `id, paper_id, user_id, COALESCE(chunk_id, location->>'chunk_id', ''),
+	COALESCE(chunk_version, 0), reverts_edit_id, location, old_text, new_text, created_at`
+
+// scanEdit scans a row selected with editColumns
+func scanEdit(row pgx.Row) (*Edit, error) {
+	var e Edit
+	err := row.Scan(&e.ID, &e.PaperID, &e.UserID, &e.ChunkID, &e.ChunkVersion,
+		&e.RevertsEditID, &e.Location, &e.OldText, &e.NewText, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EnsureUser makes sure an authenticated user has a users row for edits to reference
+func (m *Models) EnsureUser(ctx context.Context, id uuid.UUID, email string) error {
+	// Tokens without an email still need a unique placeholder for the NOT NULL column
+	if email == "" {
+		email = id.String() + "@users.invalid"
+	}
+
+	query := `INSERT INTO users (id, email) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := m.conn.GetPool().Exec(ctx, query, id, email)
+	return err
+}
+
+// ApplyEdit replaces the text of edit.ChunkID and records the change in the
+// edits table. The chunk row is locked so concurrent edits serialize; if its
+// text no longer equals edit.OldText, or baseVersion is non-zero and differs
+// from the chunk's version, an EditConflictError is returned.
+func (m *Models) ApplyEdit(ctx context.Context, edit *Edit, baseVersion int) error {
+	tx, err := m.conn.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := applyEdit(ctx, tx, edit, baseVersion); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevertEdits undoes the edits of a paper from fromID through throughID
+// (inclusive, by application order) by applying their inverses newest first
+// in one transaction. History is never deleted: each inverse is a new edit
+// pointing at the edit it reverts, so reverting an inverse redoes the
+// original. A zero throughID reverts fromID alone.
+func (m *Models) RevertEdits(ctx context.Context, paperID, userID, fromID, throughID uuid.UUID) ([]Edit, error) {
+	if throughID == uuid.Nil {
+		throughID = fromID
+	}
+
+	tx, err := m.conn.GetPool().Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var fromSeq, throughSeq int64
+	seqQuery := `SELECT seq FROM edits WHERE id = $1 AND paper_id = $2`
+	if err := tx.QueryRow(ctx, seqQuery, fromID, paperID).Scan(&fromSeq); err != nil {
+		return nil, notFound(err)
+	}
+	if err := tx.QueryRow(ctx, seqQuery, throughID, paperID).Scan(&throughSeq); err != nil {
+		return nil, notFound(err)
+	}
+	if fromSeq > throughSeq {
+		fromSeq, throughSeq = throughSeq, fromSeq
+	}
+
+	query := `SELECT ` + editColumns + `
+		FROM edits WHERE paper_id = $1 AND seq BETWEEN $2 AND $3
+		ORDER BY seq DESC`
+
+	rows, err := tx.Query(ctx, query, paperID, fromSeq, throughSeq)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Edit
+	for rows.Next() {
+		e, err := scanEdit(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, *e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	inverses := make([]Edit, 0, len(targets))
+	for i := range targets {
+		target := &targets[i]
+		inverse := Edit{
+			PaperID:       paperID,
+			UserID:        userID,
+			ChunkID:       target.ChunkID,
+			RevertsEditID: &target.ID,
+			OldText:       target.NewText,
+			NewText:       target.OldText,
+		}
+		if err := applyEdit(ctx, tx, &inverse, 0); err != nil {
+			var conflict *EditConflictError
+			if errors.As(err, &conflict) {
+				conflict.EditID = &target.ID
+			}
+			return nil, err
+		}
+		inverses = append(inverses, inverse)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return inverses, nil
+}
+
+// applyEdit checks and applies a single edit inside tx
+func applyEdit(ctx context.Context, tx pgx.Tx, edit *Edit, baseVersion int) error {
+	var chunk Chunk
+	err := tx.QueryRow(ctx, `
+		SELECT chunk_id, page, paragraph_index, sentence_index, text, version
+		FROM chunks WHERE chunk_id = $1 AND paper_id = $2
+		FOR UPDATE
+	`, edit.ChunkID, edit.PaperID).Scan(&chunk.ChunkID, &chunk.Page, &chunk.ParagraphIndex,
+		&chunk.SentenceIndex, &chunk.Text, &chunk.Version)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if chunk.Text != edit.OldText || (baseVersion != 0 && baseVersion != chunk.Version) {
+		return &EditConflictError{
+			ChunkID:        chunk.ChunkID,
+			CurrentText:    chunk.Text,
+			CurrentVersion: chunk.Version,
+		}
+	}
+
+	location, err := json.Marshal(EditLocation{
+		ChunkID: chunk.ChunkID,
+		ChunkLocation: ChunkLocation{
+			Page:           chunk.Page,
+			ParagraphIndex: chunk.ParagraphIndex,
+			SentenceIndex:  chunk.SentenceIndex,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE chunks SET text = $1, version = version + 1
+		WHERE chunk_id = $2
+		RETURNING version
+	`, edit.NewText, chunk.ChunkID).Scan(&edit.ChunkVersion)
+	if err != nil {
+		return err
+	}
+
+	edit.ID = uuid.New()
+	edit.Location = location
+	edit.CreatedAt = time.Now()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO edits (id, paper_id, user_id, chunk_id, chunk_version, reverts_edit_id,
+			location, old_text, new_text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, edit.ID, edit.PaperID, edit.UserID, edit.ChunkID, edit.ChunkVersion, edit.RevertsEditID,
+		edit.Location, edit.OldText, edit.NewText, edit.CreatedAt)
+	return err
+}
+
+// ListEdits retrieves a paper's edit history, newest first
+func (m *Models) ListEdits(ctx context.Context, paperID uuid.UUID, limit, offset int) ([]Edit, error) {
+	query := `SELECT ` + editColumns + `
+		FROM edits WHERE paper_id = $1
+		ORDER BY seq DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, paperID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []Edit
+	for rows.Next() {
+		e, err := scanEdit(rows)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, *e)
+	}
+
+	return edits, rows.Err()
+}
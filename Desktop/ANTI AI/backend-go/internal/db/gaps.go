@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// gapColumns lists the gaps columns in the order scanGaps expects
+const gapColumns = `id, paper_ids, statement, evidence, COALESCE(score, 0), COALESCE(rationale, ''),
+	COALESCE(topic, ''), COALESCE(years_window, 0), COALESCE(set_key, ''), created_at`
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// GapMatch selects how a paper set is compared with a gap's paper_ids
+type GapMatch string
+
+// Gap match modes
+const (
+	// GapMatchAny finds gaps that involve at least one of the papers
+	GapMatchAny GapMatch = "any"
+	// GapMatchAll finds gaps whose paper set contains every one of the papers
+	GapMatchAll GapMatch = "all"
+	// GapMatchExact finds gaps whose paper set is exactly the papers
+	GapMatchExact GapMatch = "exact"
+)
+
+// GapFilter selects gaps by paper set and optionally topic
+type GapFilter struct {
+	PaperIDs []uuid.UUID
+	Match    GapMatch
+	Topic    string
+	Limit    int
+	Offset   int
+}
+
+// gapMatchClause returns the WHERE condition for a match mode. $1 is the paper
+// IDs as a JSONB array for containment modes and as text[] for GapMatchAny.
+// The GIN index on paper_ids serves ?| and @>. It cannot serve <@, so exact
+// matches find candidates through @> and recheck <@ on those rows.
+func gapMatchClause(match GapMatch) (string, error) {
+	switch match {
+	case GapMatchAny:
+		return `paper_ids ?| $1::text[]`, nil
+	case GapMatchAll:
+		return `paper_ids @> $1::jsonb`, nil
+	case GapMatchExact:
+		// JSONB array containment ignores order and duplicates, so mutual containment is set equality
+		return `paper_ids @> $1::jsonb AND paper_ids <@ $1::jsonb`, nil
+	default:
+		return "", fmt.Errorf("unknown gap match mode %q", match)
+	}
+}
+
+// GetGapsByPaperIDs retrieves gaps whose paper set matches the filter, highest score
+// first, along with the total number of matches
+func (m *Models) GetGapsByPaperIDs(ctx context.Context, filter GapFilter) ([]Gap, int, error) {
+	if len(filter.PaperIDs) == 0 {
+		return nil, 0, errors.New("at least one paper ID is required")
+	}
+
+	clause, err := gapMatchClause(filter.Match)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, len(filter.PaperIDs))
+	for i, id := range filter.PaperIDs {
+		ids[i] = id.String()
+	}
+
+	var idsParam interface{} = ids
+	if filter.Match != GapMatchAny {
+		encoded, err := json.Marshal(ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		idsParam = string(encoded)
+	}
+
+	where := clause + ` AND ($2 = '' OR topic = $2)`
+
+	var total int
+	err = m.conn.GetPool().QueryRow(ctx, `SELECT COUNT(*) FROM gaps WHERE `+where, idsParam, filter.Topic).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = total
+	}
+
+	query := `SELECT ` + gapColumns + ` FROM gaps WHERE ` + where + `
+		ORDER BY score DESC, created_at, id
+		LIMIT $3 OFFSET $4`
+	rows, err := m.conn.GetPool().Query(ctx, query, idsParam, filter.Topic, limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	gaps, err := scanGaps(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return gaps, total, nil
+}
+
+// GapRun identifies one gap-finding run: a paper set, topic and years window
+type GapRun struct {
+	PaperIDs    []uuid.UUID
+	Topic       string
+	YearsWindow int
+}
+
+// SortedPaperIDs returns the run's paper IDs sorted and de-duplicated
+func (r GapRun) SortedPaperIDs() []uuid.UUID {
+	ids := make([]string, 0, len(r.PaperIDs))
+	seen := make(map[uuid.UUID]bool, len(r.PaperIDs))
+	for _, id := range r.PaperIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id.String())
+		}
+	}
+	sort.Strings(ids)
+
+	sorted := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		sorted[i] = uuid.MustParse(id)
+	}
+	return sorted
+}
+
+// SetKey returns a stable key for the run's paper set, independent of order
+func (r GapRun) SetKey() string {
+	ids := r.SortedPaperIDs()
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id.String()
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReplaceGaps atomically swaps the stored gaps of a run for a fresh set
+func (m *Models) ReplaceGaps(ctx context.Context, run GapRun, gaps []Gap) error {
+	tx, err := m.conn.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	setKey := run.SetKey()
+	paperIDs, err := json.Marshal(run.SortedPaperIDs())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`DELETE FROM gaps WHERE set_key = $1 AND topic = $2 AND years_window = $3`,
+		setKey, run.Topic, run.YearsWindow)
+	if err != nil {
+		return err
+	}
+
+	for i := range gaps {
+		gaps[i].PaperIDs = paperIDs
+		gaps[i].Topic = run.Topic
+		gaps[i].YearsWindow = run.YearsWindow
+		gaps[i].SetKey = setKey
+		if err := createGap(ctx, tx, &gaps[i]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListRunGaps pages through a run's gaps by descending score and returns the total count
+func (m *Models) ListRunGaps(ctx context.Context, run GapRun, limit, offset int) ([]Gap, int, error) {
+	setKey := run.SetKey()
+
+	var total int
+	err := m.conn.GetPool().QueryRow(ctx,
+		`SELECT COUNT(*) FROM gaps WHERE set_key = $1 AND topic = $2 AND years_window = $3`,
+		setKey, run.Topic, run.YearsWindow).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + gapColumns + ` FROM gaps
+		WHERE set_key = $1 AND topic = $2 AND years_window = $3
+		ORDER BY score DESC, created_at, id
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := m.conn.GetPool().Query(ctx, query, setKey, run.Topic, run.YearsWindow, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	gaps, err := scanGaps(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return gaps, total, nil
+}
+
+// createGap inserts a gap through the pool or a transaction
+func createGap(ctx context.Context, q execer, gap *Gap) error {
+	query := `
+		INSERT INTO gaps (id, paper_ids, statement, evidence, score, rationale, topic, years_window, set_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+	`
+
+	gap.ID = uuid.New()
+	gap.CreatedAt = time.Now()
+
+	_, err := q.Exec(ctx, query,
+		gap.ID, gap.PaperIDs, gap.Statement, gap.Evidence, gap.Score,
+		gap.Rationale, gap.Topic, gap.YearsWindow, gap.SetKey, gap.CreatedAt)
+
+	return err
+}
+
+// scanGaps scans and closes rows selected with gapColumns
+func scanGaps(rows pgx.Rows) ([]Gap, error) {
+	defer rows.Close()
+
+	var gaps []Gap
+	for rows.Next() {
+		var gap Gap
+		err := rows.Scan(&gap.ID, &gap.PaperIDs, &gap.Statement, &gap.Evidence, &gap.Score,
+			&gap.Rationale, &gap.Topic, &gap.YearsWindow, &gap.SetKey, &gap.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, gap)
+	}
+
+	return gaps, rows.Err()
+}
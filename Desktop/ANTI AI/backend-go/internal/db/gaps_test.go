@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// gapsSchema mirrors the gaps table and index the migrations build
+var gapsSchema = []string{
+	`CREATE TABLE gaps (
+		id UUID PRIMARY KEY,
+		paper_ids JSONB NOT NULL,
+		statement TEXT NOT NULL,
+		evidence JSONB,
+		score NUMERIC(3,2),
+		rationale TEXT,
+		topic TEXT NOT NULL DEFAULT '',
+		years_window INTEGER NOT NULL DEFAULT 0,
+		set_key TEXT,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	)`,
+	`CREATE INDEX idx_gaps_paper_ids ON gaps USING GIN (paper_ids)`,
+}
+
+func TestGetGapsByPaperIDs(t *testing.T) {
+	models := testModels(t, gapsSchema...)
+	ctx := context.Background()
+
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stored := map[string][]uuid.UUID{
+		"ab":     {a, b},
+		"ba-dup": {b, a, a},
+		"abc":    {a, b, c},
+		"a":      {a},
+		"cd":     {c, d},
+		"empty":  {},
+	}
+	for statement, ids := range stored {
+		paperIDs, err := json.Marshal(ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gap := &Gap{PaperIDs: paperIDs, Statement: statement, Score: 0.5}
+		if err := models.CreateGap(ctx, gap); err != nil {
+			t.Fatalf("create gap %s: %v", statement, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		match GapMatch
+		ids   []uuid.UUID
+		want  []string
+	}{
+		{"any pair", GapMatchAny, []uuid.UUID{a, b}, []string{"a", "ab", "abc", "ba-dup"}},
+		{"any reordered", GapMatchAny, []uuid.UUID{b, a}, []string{"a", "ab", "abc", "ba-dup"}},
+		{"any duplicates", GapMatchAny, []uuid.UUID{d, d}, []string{"cd"}},
+		{"any unknown", GapMatchAny, []uuid.UUID{uuid.New()}, nil},
+		{"all pair", GapMatchAll, []uuid.UUID{a, b}, []string{"ab", "abc", "ba-dup"}},
+		{"all reordered duplicates", GapMatchAll, []uuid.UUID{b, a, a}, []string{"ab", "abc", "ba-dup"}},
+		{"all subset of every set", GapMatchAll, []uuid.UUID{a}, []string{"a", "ab", "abc", "ba-dup"}},
+		{"all superset of stored", GapMatchAll, []uuid.UUID{a, b, c, d}, nil},
+		{"exact pair", GapMatchExact, []uuid.UUID{a, b}, []string{"ab", "ba-dup"}},
+		{"exact reordered duplicates", GapMatchExact, []uuid.UUID{b, a, b}, []string{"ab", "ba-dup"}},
+		{"exact single", GapMatchExact, []uuid.UUID{a}, []string{"a"}},
+		{"exact superset", GapMatchExact, []uuid.UUID{a, b, c}, []string{"abc"}},
+		{"exact superset of stored", GapMatchExact, []uuid.UUID{a, b, c, d}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaps, total, err := models.GetGapsByPaperIDs(ctx, GapFilter{PaperIDs: tt.ids, Match: tt.match})
+			if err != nil {
+				t.Fatalf("GetGapsByPaperIDs: %v", err)
+			}
+
+			var got []string
+			for _, gap := range gaps {
+				got = append(got, gap.Statement)
+			}
+			sort.Strings(got)
+
+			if len(got) != len(tt.want) || total != len(tt.want) {
+				t.Fatalf("got %v (total %d), want %v", got, total, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, match := range []GapMatch{GapMatchAny, GapMatchAll, GapMatchExact} {
+		if _, _, err := models.GetGapsByPaperIDs(ctx, GapFilter{Match: match}); err == nil {
+			t.Errorf("%s with no paper IDs: expected an error", match)
+		}
+	}
+}
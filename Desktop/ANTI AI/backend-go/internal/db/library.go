@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// LibraryHit is a chunk matching a library search
+type LibraryHit struct {
+	ChunkID        string
+	PaperID        uuid.UUID
+	PaperTitle     string
+	DOI            string
+	Page           int
+	ParagraphIndex int
+	SentenceIndex  int
+	Text           string
+	// Headline holds the matching fragments, each match between
+	// HeadlineStart and HeadlineStop
+	Headline string
+	Rank     float64
+}
+
+// Markers ts_headline puts around matches; control characters cannot be
+// confused with chunk text, so callers can escape the rest safely
+const (
+	HeadlineStart = "\x02"
+	HeadlineStop  = "\x03"
+)
+
+// headlineOptions configures ts_headline fragments
+const headlineOptions = "StartSel=" + HeadlineStart + ", StopSel=" + HeadlineStop +
+	", MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" ... \""
+
+// AddToLibrary records that a user has a paper in their library
+func (m *Models) AddToLibrary(ctx context.Context, userID, paperID uuid.UUID) error {
+	query := `INSERT INTO user_papers (user_id, paper_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := m.conn.GetPool().Exec(ctx, query, userID, paperID)
+	return err
+}
+
+// InLibrary reports whether a paper is in a user's library
+func (m *Models) InLibrary(ctx context.Context, userID, paperID uuid.UUID) (bool, error) {
+	var found bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_papers WHERE user_id = $1 AND paper_id = $2)`
+	err := m.conn.GetPool().QueryRow(ctx, query, userID, paperID).Scan(&found)
+	return found, err
+}
+
+// SearchLibrary finds chunks matching an English tsquery, best first, with
+// highlighted fragments. A nil userID searches every paper; otherwise only
+// papers in that user's library. It also returns the total number of hits.
+func (m *Models) SearchLibrary(ctx context.Context, tsquery string, userID *uuid.UUID, limit, offset int) ([]LibraryHit, int, error) {
+	// Rank and page first so ts_headline only runs on the returned chunks
+	query := `
+		WITH hits AS (
+			SELECT c.chunk_id, c.paper_id, c.page, c.paragraph_index, c.sentence_index, c.text,
+			       ts_rank_cd(c.text_search, q.query) AS rank,
+			       q.query,
+			       COUNT(*) OVER () AS total
+			FROM chunks c, to_tsquery('english', $1) AS q(query)
+			WHERE c.text_search @@ q.query
+			  AND ($2::uuid IS NULL OR EXISTS (
+			      SELECT 1 FROM user_papers up WHERE up.user_id = $2 AND up.paper_id = c.paper_id))
+			ORDER BY rank DESC, c.chunk_id
+			LIMIT $3 OFFSET $4
+		)
+		SELECT h.chunk_id, h.paper_id, p.title, COALESCE(p.doi, ''),
+		       h.page, h.paragraph_index, h.sentence_index, h.text,
+		       ts_headline('english', h.text, h.query, $5), h.rank, h.total
+		FROM hits h JOIN papers p ON p.id = h.paper_id
+		ORDER BY h.rank DESC, h.chunk_id
+	`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, tsquery, userID, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var hits []LibraryHit
+	total := 0
+	for rows.Next() {
+		var hit LibraryHit
+		err := rows.Scan(&hit.ChunkID, &hit.PaperID, &hit.PaperTitle, &hit.DOI,
+			&hit.Page, &hit.ParagraphIndex, &hit.SentenceIndex, &hit.Text, &hit.Headline, &hit.Rank, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, total, rows.Err()
+}
+
+// StoragePathInLibrary reports whether a paper in a user's library holds the
+// object at path
+func (m *Models) StoragePathInLibrary(ctx context.Context, userID uuid.UUID, path string) (bool, error) {
+	var found bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM papers p JOIN user_papers up ON up.paper_id = p.id
+			WHERE up.user_id = $1 AND p.storage_path = $2
+		)
+	`
+	err := m.conn.GetPool().QueryRow(ctx, query, userID, path).Scan(&found)
+	return found, err
+}
+
+// LibraryPaperIDs lists the papers in a user's library
+func (m *Models) LibraryPaperIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT paper_id FROM user_papers WHERE user_id = $1`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetLibraryChunks loads chunks by ID with their papers, without headlines.
+// A nil userID allows any paper; otherwise chunks outside the user's library
+// are left out, as are IDs with no chunk.
+func (m *Models) GetLibraryChunks(ctx context.Context, chunkIDs []string, userID *uuid.UUID) ([]LibraryHit, error) {
+	query := `
+		SELECT c.chunk_id, c.paper_id, p.title, COALESCE(p.doi, ''),
+		       c.page, c.paragraph_index, c.sentence_index, c.text
+		FROM chunks c JOIN papers p ON p.id = c.paper_id
+		WHERE c.chunk_id = ANY($1)
+		  AND ($2::uuid IS NULL OR EXISTS (
+		      SELECT 1 FROM user_papers up WHERE up.user_id = $2 AND up.paper_id = c.paper_id))
+	`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, chunkIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []LibraryHit
+	for rows.Next() {
+		var hit LibraryHit
+		err := rows.Scan(&hit.ChunkID, &hit.PaperID, &hit.PaperTitle, &hit.DOI,
+			&hit.Page, &hit.ParagraphIndex, &hit.SentenceIndex, &hit.Text)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stuckPaperCondition matches papers whose ingest has not moved since a cutoff
+// ($1) and that no live ingest job will pick up again. Only papers with an
+// ingest job are considered: uploads finalized without ingest stay pending
+// until someone asks for one, and are not stuck.
+const stuckPaperCondition = `
+	ingest_status IN ('pending', 'processing')
+	AND updated_at < $1
+	AND EXISTS (
+		SELECT 1 FROM jobs
+		WHERE jobs.type = 'ingest'
+		  AND jobs.payload->>'paperId' = papers.id::text
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM jobs
+		WHERE jobs.type = 'ingest'
+		  AND jobs.status IN ('queued', 'processing', 'waiting')
+		  AND jobs.payload->>'paperId' = papers.id::text
+	)`
+
+// ListStuckPapers returns papers stuck in pending or processing since before olderThan
+func (m *Models) ListStuckPapers(ctx context.Context, olderThan time.Time) ([]Paper, error) {
+	query := `SELECT ` + paperColumns + ` FROM papers WHERE` + stuckPaperCondition + `
+		ORDER BY updated_at`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var papers []Paper
+	for rows.Next() {
+		paper, err := scanPaper(rows)
+		if err != nil {
+			return nil, err
+		}
+		papers = append(papers, *paper)
+	}
+
+	return papers, rows.Err()
+}
+
+// FailStuckPapers marks the given papers failed if they are still stuck,
+// returning how many were updated
+func (m *Models) FailStuckPapers(ctx context.Context, ids []uuid.UUID, olderThan time.Time) (int64, error) {
+	query := `UPDATE papers SET ingest_status = 'failed', updated_at = NOW()
+		WHERE id = ANY($2) AND` + stuckPaperCondition
+
+	tag, err := m.conn.GetPool().Exec(ctx, query, olderThan, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReferencedStoragePaths returns which of paths are the storage_path of some paper
+func (m *Models) ReferencedStoragePaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	query := `SELECT DISTINCT storage_path FROM papers WHERE storage_path = ANY($1)`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, paths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		referenced[path] = true
+	}
+
+	return referenced, rows.Err()
+}
+
+// CountExpiredCache counts api_cache rows that expired before cutoff
+func (m *Models) CountExpiredCache(ctx context.Context, cutoff time.Time) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM api_cache WHERE expires_at < $1`
+	err := m.conn.GetPool().QueryRow(ctx, query, cutoff).Scan(&count)
+	return count, err
+}
+
+// PurgeExpiredCache deletes api_cache rows that expired before cutoff
+func (m *Models) PurgeExpiredCache(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM api_cache WHERE expires_at < $1`
+	tag, err := m.conn.GetPool().Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
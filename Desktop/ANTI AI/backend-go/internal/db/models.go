@@ -3,11 +3,24 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// notFound maps pgx's no-rows error onto ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
 // Models provides access to all database operations
 type Models struct {
 	conn *Connection
@@ -18,6 +31,11 @@ func NewModels(conn *Connection) *Models {
 	return &Models{conn: conn}
 }
 
+// Ping checks that the database behind the models is reachable
+func (m *Models) Ping(ctx context.Context) error {
+	return m.conn.Ping(ctx)
+}
+
 // Paper represents a research paper
 type Paper struct {
 	ID           uuid.UUID       `json:"id"`
@@ -27,6 +45,7 @@ type Paper struct {
 	Year         int             `json:"year"`
 	OAPDFURL     *string         `json:"oa_pdf_url"`
 	StoragePath  *string         `json:"storage_path"`
+	ContentHash  *string         `json:"content_hash"`
 	IngestedAt   *time.Time      `json:"ingested_at"`
 	IngestStatus string          `json:"ingest_status"`
 	Summary      json.RawMessage `json:"summary"`
@@ -42,46 +61,64 @@ type Chunk struct {
 	ParagraphIndex int       `json:"paragraph_index"`
 	SentenceIndex  int       `json:"sentence_index"`
 	Text           string    `json:"text"`
+	Version        int       `json:"version"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
 // Job represents a background job
 type Job struct {
-	JobID     uuid.UUID       `json:"job_id"`
-	Type      string          `json:"type"`
-	Status    string          `json:"status"`
-	Progress  int             `json:"progress"`
-	Result    json.RawMessage `json:"result"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	JobID          uuid.UUID       `json:"job_id"`
+	Type           string          `json:"type"`
+	Status         string          `json:"status"`
+	Progress       int             `json:"progress"`
+	Result         json.RawMessage `json:"result"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	RunAt          time.Time       `json:"run_at"`
+	LockedBy       *string         `json:"locked_by"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at"`
+	LastError      *string         `json:"last_error"`
+	CreatedBy      *string         `json:"created_by"`
+	DedupeKey      *string         `json:"dedupe_key,omitempty"`
+	SharedWith     []string        `json:"shared_with,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
 }
 
 // Gap represents a research gap
 type Gap struct {
-	ID        uuid.UUID       `json:"id"`
-	PaperIDs  json.RawMessage `json:"paper_ids"`
-	Statement string          `json:"statement"`
-	Evidence  json.RawMessage `json:"evidence"`
-	Score     float64         `json:"score"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID          uuid.UUID       `json:"id"`
+	PaperIDs    json.RawMessage `json:"paper_ids"`
+	Statement   string          `json:"statement"`
+	Evidence    json.RawMessage `json:"evidence"`
+	Score       float64         `json:"score"`
+	Rationale   string          `json:"rationale"`
+	Topic       string          `json:"topic"`
+	YearsWindow int             `json:"years_window"`
+	SetKey      string          `json:"set_key"`
+	CreatedAt   time.Time       `json:"created_at"`
 }
 
 // Edit represents a user edit to a paper
 type Edit struct {
-	ID        uuid.UUID       `json:"id"`
-	PaperID   uuid.UUID       `json:"paper_id"`
-	UserID    uuid.UUID       `json:"user_id"`
-	Location  json.RawMessage `json:"location"`
-	OldText   string          `json:"old_text"`
-	NewText   string          `json:"new_text"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID            uuid.UUID       `json:"id"`
+	PaperID       uuid.UUID       `json:"paper_id"`
+	UserID        uuid.UUID       `json:"user_id"`
+	ChunkID       string          `json:"chunk_id"`
+	ChunkVersion  int             `json:"chunk_version"`
+	RevertsEditID *uuid.UUID      `json:"reverts_edit_id,omitempty"`
+	Location      json.RawMessage `json:"location"`
+	OldText       string          `json:"old_text"`
+	NewText       string          `json:"new_text"`
+	CreatedAt     time.Time       `json:"created_at"`
 }
 
 // CreatePaper creates a new paper record
 func (m *Models) CreatePaper(ctx context.Context, paper *Paper) error {
 	query := `
 		INSERT INTO papers (id, doi, title, authors, year, oa_pdf_url, storage_path, ingest_status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	paper.ID = uuid.New()
@@ -96,38 +133,57 @@ func (m *Models) CreatePaper(ctx context.Context, paper *Paper) error {
 	return err
 }
 
-// GetPaperByID retrieves a paper by its ID
-func (m *Models) GetPaperByID(ctx context.Context, id uuid.UUID) (*Paper, error) {
-	query := `SELECT * FROM papers WHERE id = $1`
+// paperColumns lists the papers columns in the order scanPaper expects
+const paperColumns = `id, COALESCE(doi, ''), title, authors, COALESCE(year, 0), oa_pdf_url, storage_path,
+	content_hash, ingested_at, ingest_status, summary, created_at, updated_at`
 
+// scanPaper scans a row selected with paperColumns
+func scanPaper(row pgx.Row) (*Paper, error) {
 	var paper Paper
-	err := m.conn.GetPool().QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&paper.ID, &paper.DOI, &paper.Title, &paper.Authors, &paper.Year,
-		&paper.OAPDFURL, &paper.StoragePath, &paper.IngestedAt, &paper.IngestStatus,
+		&paper.OAPDFURL, &paper.StoragePath, &paper.ContentHash, &paper.IngestedAt, &paper.IngestStatus,
 		&paper.Summary, &paper.CreatedAt, &paper.UpdatedAt)
 
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &paper, nil
 }
 
+// GetPaperByID retrieves a paper by its ID
+func (m *Models) GetPaperByID(ctx context.Context, id uuid.UUID) (*Paper, error) {
+	query := `SELECT ` + paperColumns + ` FROM papers WHERE id = $1`
+	return scanPaper(m.conn.GetPool().QueryRow(ctx, query, id))
+}
+
 // GetPaperByDOI retrieves a paper by its DOI
 func (m *Models) GetPaperByDOI(ctx context.Context, doi string) (*Paper, error) {
-	query := `SELECT * FROM papers WHERE doi = $1`
+	query := `SELECT ` + paperColumns + ` FROM papers WHERE doi = $1`
+	return scanPaper(m.conn.GetPool().QueryRow(ctx, query, doi))
+}
 
-	var paper Paper
-	err := m.conn.GetPool().QueryRow(ctx, query, doi).Scan(
-		&paper.ID, &paper.DOI, &paper.Title, &paper.Authors, &paper.Year,
-		&paper.OAPDFURL, &paper.StoragePath, &paper.IngestedAt, &paper.IngestStatus,
-		&paper.Summary, &paper.CreatedAt, &paper.UpdatedAt)
+// IngestedDOIs returns which of dois belong to fully ingested papers
+func (m *Models) IngestedDOIs(ctx context.Context, dois []string) (map[string]bool, error) {
+	query := `SELECT doi FROM papers WHERE doi = ANY($1) AND ingest_status = 'completed'`
 
+	rows, err := m.conn.GetPool().Query(ctx, query, dois)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &paper, nil
+	ingested := make(map[string]bool)
+	for rows.Next() {
+		var doi string
+		if err := rows.Scan(&doi); err != nil {
+			return nil, err
+		}
+		ingested[doi] = true
+	}
+
+	return ingested, rows.Err()
 }
 
 // UpdatePaperStatus updates a paper's ingest status
@@ -137,25 +193,108 @@ func (m *Models) UpdatePaperStatus(ctx context.Context, id uuid.UUID, status str
 	return err
 }
 
-// CreateChunk creates a new chunk record
-func (m *Models) CreateChunk(ctx context.Context, chunk *Chunk) error {
+// GetPaperByContentHash retrieves the paper holding a PDF with the given
+// SHA-256, preferring one that has already been ingested
+func (m *Models) GetPaperByContentHash(ctx context.Context, hash string) (*Paper, error) {
+	query := `SELECT ` + paperColumns + ` FROM papers WHERE content_hash = $1
+		ORDER BY (ingest_status = 'completed') DESC, created_at
+		LIMIT 1`
+	return scanPaper(m.conn.GetPool().QueryRow(ctx, query, hash))
+}
+
+// SetPaperContent records where a paper's PDF lives and its SHA-256. It
+// reports whether this replaced a PDF with different content, in which case
+// the old digest is kept in previous_content_hash.
+func (m *Models) SetPaperContent(ctx context.Context, id uuid.UUID, storagePath, hash string) (bool, error) {
 	query := `
-		INSERT INTO chunks (chunk_id, paper_id, page, paragraph_index, sentence_index, text, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		WITH old AS (SELECT content_hash FROM papers WHERE id = $4 FOR UPDATE)
+		UPDATE papers p
+		SET storage_path = $1,
+		    content_hash = $2,
+		    previous_content_hash = CASE WHEN old.content_hash <> $2 THEN old.content_hash ELSE p.previous_content_hash END,
+		    content_changed_at = CASE WHEN old.content_hash <> $2 THEN $3 ELSE p.content_changed_at END,
+		    updated_at = $3
+		FROM old
+		WHERE p.id = $4
+		RETURNING COALESCE(old.content_hash <> $2, false)
 	`
 
+	var changed bool
+	err := m.conn.GetPool().QueryRow(ctx, query, storagePath, hash, time.Now(), id).Scan(&changed)
+	if err != nil {
+		return false, notFound(err)
+	}
+	return changed, nil
+}
+
+// markIngestedQuery records an ingest outcome, stamping ingested_at on success
+const markIngestedQuery = `
+	UPDATE papers
+	SET ingest_status = $1,
+	    ingested_at = CASE WHEN $1 = 'completed' THEN $2 ELSE ingested_at END,
+	    updated_at = $2
+	WHERE id = $3
+`
+
+// MarkPaperIngested records the outcome of an ingest and stamps ingested_at on success
+func (m *Models) MarkPaperIngested(ctx context.Context, id uuid.UUID, status string) error {
+	_, err := m.conn.GetPool().Exec(ctx, markIngestedQuery, status, time.Now(), id)
+	return err
+}
+
+// createChunkQuery inserts a chunk unless one with its ID exists
+const createChunkQuery = `
+	INSERT INTO chunks (chunk_id, paper_id, page, paragraph_index, sentence_index, text, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (chunk_id) DO NOTHING
+`
+
+// CreateChunk creates a new chunk record. An existing chunk with the same ID is left
+// untouched so redelivered worker results cannot clobber later corrections.
+func (m *Models) CreateChunk(ctx context.Context, chunk *Chunk) error {
 	chunk.CreatedAt = time.Now()
 
-	_, err := m.conn.GetPool().Exec(ctx, query,
+	_, err := m.conn.GetPool().Exec(ctx, createChunkQuery,
 		chunk.ChunkID, chunk.PaperID, chunk.Page, chunk.ParagraphIndex,
 		chunk.SentenceIndex, chunk.Text, chunk.CreatedAt)
 
 	return err
 }
 
+// CompleteIngest stores the chunks an ingest produced for a paper and marks it
+// completed in one transaction, so a delivery that fails partway leaves no
+// chunks behind. Like CreateChunk it skips chunks that already exist, so a
+// repeated delivery neither duplicates chunks nor undoes corrections.
+func (m *Models) CompleteIngest(ctx context.Context, paperID uuid.UUID, chunks []Chunk) error {
+	tx, err := m.conn.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	for i := range chunks {
+		chunk := &chunks[i]
+		chunk.PaperID = paperID
+		chunk.CreatedAt = now
+		batch.Queue(createChunkQuery,
+			chunk.ChunkID, chunk.PaperID, chunk.Page, chunk.ParagraphIndex,
+			chunk.SentenceIndex, chunk.Text, chunk.CreatedAt)
+	}
+	batch.Queue(markIngestedQuery, "completed", now, paperID)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 // GetChunksByPaperID retrieves all chunks for a paper
 func (m *Models) GetChunksByPaperID(ctx context.Context, paperID uuid.UUID) ([]Chunk, error) {
-	query := `SELECT * FROM chunks WHERE paper_id = $1 ORDER BY page, paragraph_index, sentence_index`
+	query := `SELECT chunk_id, paper_id, page, paragraph_index, sentence_index, text, version, created_at
+		FROM chunks WHERE paper_id = $1 ORDER BY page, paragraph_index, sentence_index`
 
 	rows, err := m.conn.GetPool().Query(ctx, query, paperID)
 	if err != nil {
@@ -167,7 +306,7 @@ func (m *Models) GetChunksByPaperID(ctx context.Context, paperID uuid.UUID) ([]C
 	for rows.Next() {
 		var chunk Chunk
 		err := rows.Scan(&chunk.ChunkID, &chunk.PaperID, &chunk.Page,
-			&chunk.ParagraphIndex, &chunk.SentenceIndex, &chunk.Text, &chunk.CreatedAt)
+			&chunk.ParagraphIndex, &chunk.SentenceIndex, &chunk.Text, &chunk.Version, &chunk.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -177,38 +316,61 @@ func (m *Models) GetChunksByPaperID(ctx context.Context, paperID uuid.UUID) ([]C
 	return chunks, nil
 }
 
-// CreateJob creates a new job record
+// jobColumns lists the jobs columns in the order scanJob expects
+const jobColumns = `job_id, type, status, COALESCE(progress, 0), result, payload, attempts, max_attempts,
+	run_at, locked_by, lease_expires_at, last_error, created_by, dedupe_key, shared_with, created_at, updated_at`
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+	err := row.Scan(
+		&job.JobID, &job.Type, &job.Status, &job.Progress, &job.Result, &job.Payload,
+		&job.Attempts, &job.MaxAttempts, &job.RunAt, &job.LockedBy, &job.LeaseExpiresAt,
+		&job.LastError, &job.CreatedBy, &job.DedupeKey, &job.SharedWith, &job.CreatedAt, &job.UpdatedAt)
+
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &job, nil
+}
+
+// CreateJob creates a new job record. It returns ErrJobExists when the job
+// has a dedupe key that a live job of its type already holds.
 func (m *Models) CreateJob(ctx context.Context, job *Job) error {
 	query := `
-		INSERT INTO jobs (job_id, type, status, progress, result, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO jobs (job_id, type, status, progress, result, payload, max_attempts, run_at, created_by, dedupe_key, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (type, dedupe_key) WHERE status IN ('queued', 'processing', 'waiting') DO NOTHING
 	`
 
 	job.JobID = uuid.New()
 	job.CreatedAt = time.Now()
 	job.UpdatedAt = time.Now()
+	if job.RunAt.IsZero() {
+		job.RunAt = job.CreatedAt
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
 
-	_, err := m.conn.GetPool().Exec(ctx, query,
-		job.JobID, job.Type, job.Status, job.Progress, job.Result,
-		job.CreatedAt, job.UpdatedAt)
+	tag, err := m.conn.GetPool().Exec(ctx, query,
+		job.JobID, job.Type, job.Status, job.Progress, job.Result, job.Payload,
+		job.MaxAttempts, job.RunAt, job.CreatedBy, job.DedupeKey, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobExists
+	}
 
-	return err
+	return nil
 }
 
 // GetJobByID retrieves a job by its ID
 func (m *Models) GetJobByID(ctx context.Context, id uuid.UUID) (*Job, error) {
-	query := `SELECT * FROM jobs WHERE job_id = $1`
-
-	var job Job
-	err := m.conn.GetPool().QueryRow(ctx, query, id).Scan(
-		&job.JobID, &job.Type, &job.Status, &job.Progress, &job.Result,
-		&job.CreatedAt, &job.UpdatedAt)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &job, nil
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE job_id = $1`
+	return scanJob(m.conn.GetPool().QueryRow(ctx, query, id))
 }
 
 // UpdateJobStatus updates a job's status and progress
@@ -220,45 +382,5 @@ func (m *Models) UpdateJobStatus(ctx context.Context, id uuid.UUID, status strin
 
 // CreateGap creates a new gap record
 func (m *Models) CreateGap(ctx context.Context, gap *Gap) error {
-	query := `
-		INSERT INTO gaps (id, paper_ids, statement, evidence, score, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-
-	gap.ID = uuid.New()
-	gap.CreatedAt = time.Now()
-
-	_, err := m.conn.GetPool().Exec(ctx, query,
-		gap.ID, gap.PaperIDs, gap.Statement, gap.Evidence, gap.Score, gap.CreatedAt)
-
-	return err
-}
-
-// GetGapsByPaperIDs retrieves gaps for specific papers
-func (m *Models) GetGapsByPaperIDs(ctx context.Context, paperIDs []uuid.UUID) ([]Gap, error) {
-	// This is a simplified implementation - in production you'd want to use proper JSONB queries
-	query := `SELECT * FROM gaps WHERE paper_ids @> $1 ORDER BY score DESC`
-
-	paperIDsJSON, err := json.Marshal(paperIDs)
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := m.conn.GetPool().Query(ctx, query, paperIDsJSON)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var gaps []Gap
-	for rows.Next() {
-		var gap Gap
-		err := rows.Scan(&gap.ID, &gap.PaperIDs, &gap.Statement, &gap.Evidence, &gap.Score, &gap.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		gaps = append(gaps, gap)
-	}
-
-	return gaps, nil
+	return createGap(ctx, m.conn.GetPool(), gap)
 }
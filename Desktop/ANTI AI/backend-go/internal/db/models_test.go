@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// ingestSchema mirrors the paper and chunk columns an ingest writes
+var ingestSchema = []string{
+	`CREATE TABLE papers (
+		id UUID PRIMARY KEY,
+		title TEXT NOT NULL,
+		ingested_at TIMESTAMPTZ,
+		ingest_status TEXT DEFAULT 'pending',
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	)`,
+	`CREATE TABLE chunks (
+		chunk_id TEXT PRIMARY KEY,
+		paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+		page INTEGER NOT NULL,
+		paragraph_index INTEGER NOT NULL,
+		sentence_index INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		CONSTRAINT chunk_id_format CHECK (chunk_id ~ '^.+::p\d+::para\d+::s\d+$')
+	)`,
+}
+
+func TestCompleteIngest(t *testing.T) {
+	models := testModels(t, ingestSchema...)
+	ctx := context.Background()
+
+	good, bad := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{good, bad} {
+		if _, err := models.conn.GetPool().Exec(ctx, `INSERT INTO papers (id, title) VALUES ($1, 'paper')`, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := func(paperID uuid.UUID) (status string, texts map[string]string) {
+		t.Helper()
+		if err := models.conn.GetPool().QueryRow(ctx, `SELECT ingest_status FROM papers WHERE id = $1`, paperID).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := models.GetChunksByPaperID(ctx, paperID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = make(map[string]string)
+		for _, chunk := range chunks {
+			texts[chunk.ChunkID] = chunk.Text
+		}
+		return status, texts
+	}
+
+	first := []Chunk{
+		{ChunkID: "doi::p1::para1::s1", Page: 1, ParagraphIndex: 1, SentenceIndex: 1, Text: "first"},
+		{ChunkID: "doi::p1::para1::s2", Page: 1, ParagraphIndex: 1, SentenceIndex: 2, Text: "second"},
+	}
+	if err := models.CompleteIngest(ctx, good, first); err != nil {
+		t.Fatalf("CompleteIngest: %v", err)
+	}
+
+	// A redelivery neither duplicates chunks nor overwrites stored text
+	redelivered := []Chunk{
+		{ChunkID: "doi::p1::para1::s1", Page: 1, ParagraphIndex: 1, SentenceIndex: 1, Text: "changed"},
+		{ChunkID: "doi::p1::para1::s3", Page: 1, ParagraphIndex: 1, SentenceIndex: 3, Text: "third"},
+	}
+	if err := models.CompleteIngest(ctx, good, redelivered); err != nil {
+		t.Fatalf("CompleteIngest again: %v", err)
+	}
+
+	status, texts := state(good)
+	if status != "completed" {
+		t.Errorf("status = %q, want completed", status)
+	}
+	want := map[string]string{"doi::p1::para1::s1": "first", "doi::p1::para1::s2": "second", "doi::p1::para1::s3": "third"}
+	if len(texts) != len(want) {
+		t.Fatalf("chunks = %v, want %v", texts, want)
+	}
+	for id, text := range want {
+		if texts[id] != text {
+			t.Errorf("chunk %s = %q, want %q", id, texts[id], text)
+		}
+	}
+
+	// A delivery that fails partway stores nothing and leaves the paper pending
+	failing := []Chunk{
+		{ChunkID: "doi2::p1::para1::s1", Page: 1, ParagraphIndex: 1, SentenceIndex: 1, Text: "kept?"},
+		{ChunkID: "malformed", Page: 1, ParagraphIndex: 1, SentenceIndex: 2, Text: "rejected"},
+	}
+	if err := models.CompleteIngest(ctx, bad, failing); err == nil {
+		t.Fatal("expected the malformed chunk to fail the delivery")
+	}
+	status, texts = state(bad)
+	if status != "pending" || len(texts) != 0 {
+		t.Errorf("after failed delivery: status %q, chunks %v; want pending with none", status, texts)
+	}
+}
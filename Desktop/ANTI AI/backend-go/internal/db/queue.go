@@ -0,0 +1,287 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses used by the queue
+const (
+	JobQueued     = "queued"
+	JobProcessing = "processing"
+	JobWaiting    = "waiting"
+	JobCompleted  = "completed"
+	JobDead       = "dead"
+)
+
+// DefaultMaxAttempts is used when a job is enqueued without an explicit limit
+const DefaultMaxAttempts = 5
+
+// ErrLeaseLost is returned when a worker touches a job it no longer holds
+var ErrLeaseLost = errors.New("job lease lost")
+
+// ErrJobExists is returned when a live job already holds a new job's dedupe key
+var ErrJobExists = errors.New("a live job with this dedupe key exists")
+
+// enqueueRetries bounds how often EnqueueUniqueJob retries when the live job it
+// collided with finishes before it can be joined
+const enqueueRetries = 3
+
+// EnqueueJob stores a new queued job carrying the given payload. createdBy is
+// the token subject queuing it, or empty when there is none.
+func (m *Models) EnqueueJob(ctx context.Context, jobType string, payload interface{}, maxAttempts int, createdBy string) (*Job, error) {
+	job, err := newJob(jobType, payload, maxAttempts, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// EnqueueUniqueJob queues a job like EnqueueJob unless a job of the same type
+// and dedupeKey is still queued, processing or waiting. Then that job is
+// returned instead, with created false, and createdBy may see its status.
+func (m *Models) EnqueueUniqueJob(ctx context.Context, jobType, dedupeKey string, payload interface{}, maxAttempts int, createdBy string) (*Job, bool, error) {
+	job, err := newJob(jobType, payload, maxAttempts, createdBy)
+	if err != nil {
+		return nil, false, err
+	}
+	job.DedupeKey = &dedupeKey
+
+	for i := 0; i < enqueueRetries; i++ {
+		err := m.CreateJob(ctx, job)
+		if err == nil {
+			return job, true, nil
+		}
+		if !errors.Is(err, ErrJobExists) {
+			return nil, false, err
+		}
+
+		live, err := m.joinLiveJob(ctx, jobType, dedupeKey, createdBy)
+		if err == nil {
+			return live, false, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, false, err
+		}
+	}
+	return nil, false, ErrJobExists
+}
+
+// newJob builds a queued job carrying payload
+func newJob(jobType string, payload interface{}, maxAttempts int, createdBy string) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &Job{
+		Type:        jobType,
+		Status:      JobQueued,
+		Payload:     data,
+		MaxAttempts: maxAttempts,
+	}
+	if createdBy != "" {
+		job.CreatedBy = &createdBy
+	}
+	return job, nil
+}
+
+// joinLiveJob returns the live job of a type holding dedupeKey, sharing it
+// with userID. Returns ErrNotFound when that job has already finished.
+func (m *Models) joinLiveJob(ctx context.Context, jobType, dedupeKey, userID string) (*Job, error) {
+	query := `
+		UPDATE jobs
+		SET shared_with = CASE
+		        WHEN $3 = '' OR created_by = $3 OR $3 = ANY(shared_with) THEN shared_with
+		        ELSE array_append(shared_with, $3)
+		    END
+		WHERE type = $1 AND dedupe_key = $2 AND status IN ('queued', 'processing', 'waiting')
+		RETURNING ` + jobColumns
+
+	return scanJob(m.conn.GetPool().QueryRow(ctx, query, jobType, dedupeKey, userID))
+}
+
+// ClaimJob leases the next runnable job of a type to workerID.
+// Queued jobs whose run_at has passed are eligible, as are processing jobs whose
+// lease expired because their previous holder died and waiting jobs whose callback
+// never arrived. Returns ErrNotFound when idle.
+func (m *Models) ClaimJob(ctx context.Context, jobType, workerID string, lease time.Duration) (*Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'processing',
+		    attempts = attempts + 1,
+		    locked_by = $2,
+		    lease_expires_at = NOW() + make_interval(secs => $3),
+		    updated_at = NOW()
+		WHERE job_id = (
+			SELECT job_id FROM jobs
+			WHERE type = $1
+			  AND ((status IN ('queued', 'waiting') AND run_at <= NOW())
+			    OR (status = 'processing' AND lease_expires_at < NOW()))
+			ORDER BY run_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + jobColumns
+
+	return scanJob(m.conn.GetPool().QueryRow(ctx, query, jobType, workerID, lease.Seconds()))
+}
+
+// HeartbeatJob extends the lease on a job still held by workerID
+func (m *Models) HeartbeatJob(ctx context.Context, id uuid.UUID, workerID string, lease time.Duration) error {
+	query := `
+		UPDATE jobs
+		SET lease_expires_at = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID, lease.Seconds())
+}
+
+// UpdateJobProgress records progress and an interim result on a leased job
+func (m *Models) UpdateJobProgress(ctx context.Context, id uuid.UUID, workerID string, progress int, result json.RawMessage) error {
+	query := `
+		UPDATE jobs
+		SET progress = $3, result = COALESCE($4, result), updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID, progress, result)
+}
+
+// CompleteJob marks a leased job completed with its final result
+func (m *Models) CompleteJob(ctx context.Context, id uuid.UUID, workerID string, result json.RawMessage) error {
+	query := `
+		UPDATE jobs
+		SET status = 'completed', progress = 100, result = $3, last_error = NULL,
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID, result)
+}
+
+// FailJob records a failed attempt. The job is re-queued after backoff, or moved
+// to the dead state once it has used all its attempts. Returns the new status.
+func (m *Models) FailJob(ctx context.Context, id uuid.UUID, workerID string, lastError string, backoff time.Duration) (string, error) {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+		    run_at = CASE WHEN attempts >= max_attempts THEN run_at ELSE NOW() + make_interval(secs => $4) END,
+		    last_error = $3,
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+		RETURNING status
+	`
+
+	var status string
+	err := m.conn.GetPool().QueryRow(ctx, query, id, workerID, lastError, backoff.Seconds()).Scan(&status)
+	if err != nil {
+		if errors.Is(notFound(err), ErrNotFound) {
+			return "", ErrLeaseLost
+		}
+		return "", err
+	}
+
+	return status, nil
+}
+
+// KillJob moves a leased job straight to the dead state, skipping any retries left
+func (m *Models) KillJob(ctx context.Context, id uuid.UUID, workerID string, lastError string) error {
+	query := `
+		UPDATE jobs
+		SET status = 'dead', last_error = $3,
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID, lastError)
+}
+
+// ParkJob releases the lease on a job that an external callback will resolve.
+// If no callback arrives within timeout the job becomes claimable again.
+func (m *Models) ParkJob(ctx context.Context, id uuid.UUID, workerID string, result json.RawMessage, timeout time.Duration) error {
+	query := `
+		UPDATE jobs
+		SET status = 'waiting', result = COALESCE($3, result),
+		    run_at = NOW() + make_interval(secs => $4),
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID, result, timeout.Seconds())
+}
+
+// CompleteWaitingJob completes a job on behalf of an external callback.
+// Processing jobs are accepted too, since the callback can beat ParkJob.
+func (m *Models) CompleteWaitingJob(ctx context.Context, id uuid.UUID, result json.RawMessage) error {
+	query := `
+		UPDATE jobs
+		SET status = 'completed', progress = 100, result = $2, last_error = NULL,
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND status IN ('waiting', 'processing')
+	`
+	return m.execResolved(ctx, query, id, result)
+}
+
+// FailWaitingJob records a failure reported by an external callback, re-queueing
+// the job after backoff or dead-lettering it when out of attempts. Returns the new status.
+func (m *Models) FailWaitingJob(ctx context.Context, id uuid.UUID, lastError string, backoff time.Duration) (string, error) {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+		    run_at = CASE WHEN attempts >= max_attempts THEN run_at ELSE NOW() + make_interval(secs => $3) END,
+		    last_error = $2,
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND status IN ('waiting', 'processing')
+		RETURNING status
+	`
+
+	var status string
+	err := m.conn.GetPool().QueryRow(ctx, query, id, lastError, backoff.Seconds()).Scan(&status)
+	if err != nil {
+		return "", notFound(err)
+	}
+
+	return status, nil
+}
+
+// ReleaseJob hands a leased job back to the queue without charging an attempt,
+// used when the API shuts down mid-task
+func (m *Models) ReleaseJob(ctx context.Context, id uuid.UUID, workerID string) error {
+	query := `
+		UPDATE jobs
+		SET status = 'queued', attempts = GREATEST(attempts - 1, 0), run_at = NOW(),
+		    locked_by = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND locked_by = $2 AND status = 'processing'
+	`
+	return m.execLeased(ctx, query, id, workerID)
+}
+
+// execLeased runs a lease-guarded update and reports ErrLeaseLost if no row matched
+func (m *Models) execLeased(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := m.conn.GetPool().Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// execResolved runs a callback-driven update and reports ErrNotFound if no row matched
+func (m *Models) execResolved(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := m.conn.GetPool().Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// jobsSchema mirrors the jobs table and indexes the migrations build
+var jobsSchema = []string{
+	`CREATE TABLE jobs (
+		job_id UUID PRIMARY KEY,
+		type TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'queued',
+		progress INTEGER DEFAULT 0,
+		result JSONB,
+		payload JSONB,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		max_attempts INTEGER NOT NULL DEFAULT 5,
+		run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		locked_by TEXT,
+		lease_expires_at TIMESTAMPTZ,
+		last_error TEXT,
+		created_by TEXT,
+		dedupe_key TEXT,
+		shared_with TEXT[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX idx_jobs_live_dedupe ON jobs(type, dedupe_key)
+		WHERE status IN ('queued', 'processing', 'waiting')`,
+}
+
+func TestEnqueueUniqueJob(t *testing.T) {
+	models := testModels(t, jobsSchema...)
+	ctx := context.Background()
+
+	first, created, err := models.EnqueueUniqueJob(ctx, "ingest", "paper-1", map[string]string{"paperId": "paper-1"}, 0, "alice")
+	if err != nil || !created {
+		t.Fatalf("first enqueue: created %v, err %v", created, err)
+	}
+
+	for _, user := range []string{"bob", "bob", "alice", ""} {
+		joined, created, err := models.EnqueueUniqueJob(ctx, "ingest", "paper-1", map[string]string{"paperId": "paper-1"}, 0, user)
+		if err != nil {
+			t.Fatalf("enqueue as %q: %v", user, err)
+		}
+		if created || joined.JobID != first.JobID {
+			t.Fatalf("enqueue as %q: got job %s (created %v), want live job %s", user, joined.JobID, created, first.JobID)
+		}
+	}
+
+	job, err := models.GetJobByID(ctx, first.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(job.SharedWith, []string{"bob"}) {
+		t.Errorf("shared with %v, want [bob]", job.SharedWith)
+	}
+
+	other, created, err := models.EnqueueUniqueJob(ctx, "ingest", "paper-2", nil, 0, "bob")
+	if err != nil || !created || other.JobID == first.JobID {
+		t.Fatalf("other key: job %v, created %v, err %v", other, created, err)
+	}
+	if _, created, err := models.EnqueueUniqueJob(ctx, "summarize", "paper-1", nil, 0, "bob"); err != nil || !created {
+		t.Fatalf("other type: created %v, err %v", created, err)
+	}
+
+	// Once the live job finishes the key is free again
+	if _, err := models.conn.GetPool().Exec(ctx, `UPDATE jobs SET status = $1 WHERE job_id = $2`, JobCompleted, first.JobID); err != nil {
+		t.Fatal(err)
+	}
+	again, created, err := models.EnqueueUniqueJob(ctx, "ingest", "paper-1", nil, 0, "bob")
+	if err != nil || !created || again.JobID == first.JobID {
+		t.Fatalf("after completion: job %v, created %v, err %v", again, created, err)
+	}
+
+	// Jobs without a key never collide
+	for i := 0; i < 2; i++ {
+		if _, err := models.EnqueueJob(ctx, "ingest", nil, 0, "alice"); err != nil {
+			t.Fatalf("unkeyed enqueue: %v", err)
+		}
+	}
+	key := "paper-1"
+	if err := models.CreateJob(ctx, &Job{Type: "ingest", Status: JobQueued, DedupeKey: &key}); !errors.Is(err, ErrJobExists) {
+		t.Errorf("CreateJob with a live key: err %v, want ErrJobExists", err)
+	}
+}
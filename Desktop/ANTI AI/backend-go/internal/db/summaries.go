@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Summary represents a stored summary variant of a paper
+type Summary struct {
+	ID          uuid.UUID       `json:"id"`
+	PaperID     uuid.UUID       `json:"paper_id"`
+	Scope       string          `json:"scope"`
+	Granularity string          `json:"granularity"`
+	Items       json.RawMessage `json:"items"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// MissingChunksError is returned when a summary cites chunks the paper does not have
+type MissingChunksError struct {
+	ChunkIDs []string
+}
+
+func (e *MissingChunksError) Error() string {
+	return fmt.Sprintf("provenance cites unknown chunks: %s", strings.Join(e.ChunkIDs, ", "))
+}
+
+// UpsertSummary stores a summary variant, replacing any previous one for the same
+// paper, scope and granularity. Every cited chunk ID must belong to the paper;
+// the cited chunks are share-locked so they cannot vanish before the summary commits.
+func (m *Models) UpsertSummary(ctx context.Context, summary *Summary, citedChunkIDs []string) error {
+	tx, err := m.conn.GetPool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(citedChunkIDs) > 0 {
+		rows, err := tx.Query(ctx,
+			`SELECT chunk_id FROM chunks WHERE paper_id = $1 AND chunk_id = ANY($2) FOR SHARE`,
+			summary.PaperID, citedChunkIDs)
+		if err != nil {
+			return err
+		}
+
+		found := make(map[string]bool, len(citedChunkIDs))
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			found[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var missing []string
+		for _, id := range citedChunkIDs {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			return &MissingChunksError{ChunkIDs: missing}
+		}
+	}
+
+	query := `
+		INSERT INTO summaries (id, paper_id, scope, granularity, items, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (paper_id, scope, granularity)
+		DO UPDATE SET items = EXCLUDED.items
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		uuid.New(), summary.PaperID, summary.Scope, summary.Granularity, summary.Items,
+	).Scan(&summary.ID, &summary.CreatedAt, &summary.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetSummary retrieves one summary variant of a paper
+func (m *Models) GetSummary(ctx context.Context, paperID uuid.UUID, scope, granularity string) (*Summary, error) {
+	query := `
+		SELECT id, paper_id, scope, granularity, items, created_at, updated_at
+		FROM summaries WHERE paper_id = $1 AND scope = $2 AND granularity = $3
+	`
+
+	var s Summary
+	err := m.conn.GetPool().QueryRow(ctx, query, paperID, scope, granularity).Scan(
+		&s.ID, &s.PaperID, &s.Scope, &s.Granularity, &s.Items, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &s, nil
+}
+
+// ListSummaries retrieves every stored summary variant of a paper
+func (m *Models) ListSummaries(ctx context.Context, paperID uuid.UUID) ([]Summary, error) {
+	query := `
+		SELECT id, paper_id, scope, granularity, items, created_at, updated_at
+		FROM summaries WHERE paper_id = $1 ORDER BY scope, granularity
+	`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, paperID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []Summary
+	for rows.Next() {
+		var s Summary
+		err := rows.Scan(&s.ID, &s.PaperID, &s.Scope, &s.Granularity, &s.Items, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// UpdatePaperSummary replaces the summary shown on the paper record
+func (m *Models) UpdatePaperSummary(ctx context.Context, paperID uuid.UUID, summary json.RawMessage) error {
+	query := `UPDATE papers SET summary = $1, updated_at = $2 WHERE id = $3`
+	_, err := m.conn.GetPool().Exec(ctx, query, summary, time.Now(), paperID)
+	return err
+}
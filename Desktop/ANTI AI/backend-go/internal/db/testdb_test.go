@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// testModels connects to the Postgres named by TEST_DATABASE_URL inside a
+// fresh schema, runs the given DDL there and drops the schema when the test
+// ends. Tests that need real Postgres semantics are skipped without it.
+func testModels(t *testing.T, ddl ...string) *Models {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	schema := fmt.Sprintf("gaply_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("create schema: %v", err)
+	}
+
+	conn, err := NewConnection(withSearchPath(dsn, schema))
+	if err != nil {
+		admin.Close(ctx)
+		t.Fatalf("connect to test schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := admin.Exec(ctx, `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+		admin.Close(ctx)
+	})
+
+	for _, stmt := range ddl {
+		if _, err := conn.GetPool().Exec(ctx, stmt); err != nil {
+			t.Fatalf("apply schema: %v", err)
+		}
+	}
+
+	return NewModels(conn)
+}
+
+// withSearchPath points a connection string at a schema
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
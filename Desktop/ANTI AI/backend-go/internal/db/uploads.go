@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RecordUpload remembers that path was issued to userID for upload, and may
+// be finalized until expiresAt
+func (m *Models) RecordUpload(ctx context.Context, path, userID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO uploads (path, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (path, user_id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	`
+	_, err := m.conn.GetPool().Exec(ctx, query, path, userID, expiresAt)
+	return err
+}
+
+// UploadIssued reports whether path was issued to userID and has not expired
+func (m *Models) UploadIssued(ctx context.Context, path, userID string) (bool, error) {
+	var issued bool
+	query := `SELECT EXISTS (SELECT 1 FROM uploads WHERE path = $1 AND user_id = $2 AND expires_at > NOW())`
+	err := m.conn.GetPool().QueryRow(ctx, query, path, userID).Scan(&issued)
+	return issued, err
+}
+
+// PendingUploadPaths reports which of paths were issued for an upload that can
+// still be finalized
+func (m *Models) PendingUploadPaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	query := `SELECT DISTINCT path FROM uploads WHERE path = ANY($1) AND expires_at > NOW()`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, paths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		pending[path] = true
+	}
+
+	return pending, rows.Err()
+}
+
+// ConsumeUpload forgets an issued upload once it has been finalized, along
+// with any of the user's records that have expired
+func (m *Models) ConsumeUpload(ctx context.Context, path, userID string) error {
+	query := `DELETE FROM uploads WHERE user_id = $2 AND (path = $1 OR expires_at <= NOW())`
+	_, err := m.conn.GetPool().Exec(ctx, query, path, userID)
+	return err
+}
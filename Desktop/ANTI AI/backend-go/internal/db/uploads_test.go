@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// uploadsSchema mirrors the uploads table
+var uploadsSchema = []string{
+	`CREATE TABLE uploads (
+		path TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		PRIMARY KEY (path, user_id)
+	)`,
+}
+
+func TestPendingUploadPaths(t *testing.T) {
+	models := testModels(t, uploadsSchema...)
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, upload := range []struct {
+		path, user string
+		expiresAt  time.Time
+	}{
+		{"uploads/live.pdf", "alice", now.Add(time.Hour)},
+		{"uploads/live.pdf", "bob", now.Add(-time.Hour)},
+		{"uploads/expired.pdf", "alice", now.Add(-time.Hour)},
+	} {
+		if err := models.RecordUpload(ctx, upload.path, upload.user, upload.expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := models.PendingUploadPaths(ctx, []string{"uploads/live.pdf", "uploads/expired.pdf", "uploads/unknown.pdf"})
+	if err != nil {
+		t.Fatalf("PendingUploadPaths: %v", err)
+	}
+	if want := map[string]bool{"uploads/live.pdf": true}; !reflect.DeepEqual(pending, want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+}
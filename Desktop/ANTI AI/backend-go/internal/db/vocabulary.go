@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+)
+
+// vocabularySources are the texts query spelling is learned from: paper
+// titles, chunk text and the titles in cached search provider pages. Each is
+// tokenized with the 'simple' configuration, which lowercases without stemming.
+const vocabularySources = `
+	SELECT to_tsvector('simple', title) FROM papers
+	UNION ALL
+	SELECT to_tsvector('simple', text) FROM chunks
+	UNION ALL
+	SELECT to_tsvector('simple', coalesce(work->>'title', ''))
+	FROM api_cache,
+	     jsonb_array_elements(CASE jsonb_typeof(response_data->'results')
+	                          WHEN 'array' THEN response_data->'results'
+	                          ELSE '[]'::jsonb END) AS work
+	WHERE cache_key LIKE '` + SearchCachePrefix + `%'
+`
+
+// WordFrequencies counts how often each word occurs across the vocabulary
+// sources, keeping words seen at least minCount times. At most limit of the
+// most frequent words are returned.
+func (m *Models) WordFrequencies(ctx context.Context, minCount, limit int) (map[string]int, error) {
+	query := `
+		SELECT word, nentry FROM ts_stat($1)
+		WHERE nentry >= $2 AND length(word) BETWEEN 2 AND 40 AND word ~ '^[[:alpha:]]'
+		ORDER BY nentry DESC
+		LIMIT $3
+	`
+
+	rows, err := m.conn.GetPool().Query(ctx, query, vocabularySources, minCount, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := make(map[string]int)
+	for rows.Next() {
+		var word string
+		var count int
+		if err := rows.Scan(&word, &count); err != nil {
+			return nil, err
+		}
+		words[word] = count
+	}
+
+	return words, rows.Err()
+}
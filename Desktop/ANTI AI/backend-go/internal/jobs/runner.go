@@ -0,0 +1,277 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Job types handled by the queue
+const (
+	TypeIngest       = "ingest"
+	TypeSummarize    = "summarize"
+	TypeGapFind      = "gapfind"
+	TypeJournalCheck = "journal_check"
+)
+
+// Handler processes a claimed task and returns the job result
+type Handler func(ctx context.Context, task *Task) (interface{}, error)
+
+// Options configures a Runner
+type Options struct {
+	// Lease is how long a claim is valid without a heartbeat
+	Lease time.Duration
+	// PollInterval is how long an idle consumer waits before claiming again
+	PollInterval time.Duration
+	// BackoffBase is the delay before the first retry; it doubles per attempt
+	BackoffBase time.Duration
+	// BackoffMax caps the retry delay
+	BackoffMax time.Duration
+}
+
+// Runner runs queue consumers for each registered job type
+type Runner struct {
+	models   *db.Models
+	opts     Options
+	workerID string
+
+	handlers map[string]registration
+	wg       sync.WaitGroup
+}
+
+// registration is a handler and the number of consumers to run for it
+type registration struct {
+	handler     Handler
+	concurrency int
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the runner dead-letters the job instead of retrying it
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// awaitError marks a task handed off to something that will resolve it via callback
+type awaitError struct {
+	result  interface{}
+	timeout time.Duration
+}
+
+func (e *awaitError) Error() string { return "awaiting callback" }
+
+// AwaitCallback parks the job until an external callback resolves it. The interim
+// result is stored on the job; if nothing calls back within timeout the job is retried.
+func AwaitCallback(result interface{}, timeout time.Duration) error {
+	return &awaitError{result: result, timeout: timeout}
+}
+
+// NewRunner creates a new Runner
+func NewRunner(models *db.Models, opts Options) *Runner {
+	if opts.Lease <= 0 {
+		opts.Lease = 2 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.BackoffBase <= 0 {
+		opts.BackoffBase = 10 * time.Second
+	}
+	if opts.BackoffMax <= 0 {
+		opts.BackoffMax = 30 * time.Minute
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &Runner{
+		models:   models,
+		opts:     opts,
+		workerID: fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		handlers: make(map[string]registration),
+	}
+}
+
+// Register adds a handler for a job type with the given number of consumers
+func (r *Runner) Register(jobType string, concurrency int, handler Handler) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	r.handlers[jobType] = registration{handler: handler, concurrency: concurrency}
+}
+
+// Start launches the consumers; they stop claiming work once ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	for jobType, reg := range r.handlers {
+		for i := 0; i < reg.concurrency; i++ {
+			r.wg.Add(1)
+			go r.consume(ctx, jobType, reg.handler)
+		}
+		log.Printf("jobs: started %d %s consumer(s)", reg.concurrency, jobType)
+	}
+}
+
+// Wait blocks until every consumer has stopped
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Backoff returns the retry delay after the given attempt
+func (r *Runner) Backoff(attempt int) time.Duration {
+	delay := r.opts.BackoffBase
+	for i := 1; i < attempt && delay < r.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > r.opts.BackoffMax {
+		delay = r.opts.BackoffMax
+	}
+	return delay
+}
+
+// consume claims and processes jobs of one type until ctx is cancelled
+func (r *Runner) consume(ctx context.Context, jobType string, handler Handler) {
+	defer r.wg.Done()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := r.models.ClaimJob(ctx, jobType, r.workerID, r.opts.Lease)
+		if err != nil {
+			if !errors.Is(err, db.ErrNotFound) && ctx.Err() == nil {
+				log.Printf("jobs: failed to claim %s job: %v", jobType, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.opts.PollInterval):
+			}
+			continue
+		}
+
+		r.process(ctx, job, handler)
+	}
+}
+
+// process runs a single claimed job, keeping its lease alive until the handler returns
+func (r *Runner) process(ctx context.Context, job *db.Job, handler Handler) {
+	// Bookkeeping must outlive a shutdown so the job is never left leased
+	bookkeeping := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), 10*time.Second)
+	}
+
+	// A lease that expired after the last attempt is reclaimed here only to be buried
+	if job.Attempts > job.MaxAttempts {
+		bctx, cancel := bookkeeping()
+		defer cancel()
+		if err := r.models.KillJob(bctx, job.JobID, r.workerID, "lease expired on final attempt"); err != nil {
+			log.Printf("jobs: failed to dead-letter %s: %v", job.JobID, err)
+		}
+		return
+	}
+
+	taskCtx, cancelTask := context.WithCancel(ctx)
+	defer cancelTask()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(taskCtx, job.JobID, cancelTask)
+	}()
+
+	task := &Task{Job: job, runner: r}
+	result, err := handler(taskCtx, task)
+
+	cancelTask()
+	<-heartbeatDone
+
+	bctx, cancel := bookkeeping()
+	defer cancel()
+
+	switch {
+	case err == nil:
+		data, mErr := json.Marshal(result)
+		if mErr != nil {
+			err = Permanent(fmt.Errorf("failed to encode result: %w", mErr))
+			break
+		}
+		if err := r.models.CompleteJob(bctx, job.JobID, r.workerID, data); err != nil {
+			log.Printf("jobs: failed to complete %s: %v", job.JobID, err)
+		}
+		return
+
+	case ctx.Err() != nil:
+		// Shutting down: hand the job back so another instance can pick it up
+		if err := r.models.ReleaseJob(bctx, job.JobID, r.workerID); err != nil {
+			log.Printf("jobs: failed to release %s: %v", job.JobID, err)
+		}
+		return
+	}
+
+	var await *awaitError
+	if errors.As(err, &await) {
+		data, mErr := json.Marshal(await.result)
+		if mErr != nil {
+			log.Printf("jobs: failed to encode interim result of %s: %v", job.JobID, mErr)
+		}
+		// ErrLeaseLost here means the callback already resolved the job
+		if pErr := r.models.ParkJob(bctx, job.JobID, r.workerID, data, await.timeout); pErr != nil && !errors.Is(pErr, db.ErrLeaseLost) {
+			log.Printf("jobs: failed to park %s: %v", job.JobID, pErr)
+		}
+		return
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		log.Printf("jobs: %s %s failed permanently: %v", job.Type, job.JobID, err)
+		if kErr := r.models.KillJob(bctx, job.JobID, r.workerID, err.Error()); kErr != nil {
+			log.Printf("jobs: failed to dead-letter %s: %v", job.JobID, kErr)
+		}
+		return
+	}
+
+	status, fErr := r.models.FailJob(bctx, job.JobID, r.workerID, err.Error(), r.Backoff(job.Attempts))
+	if fErr != nil {
+		log.Printf("jobs: failed to record failure of %s: %v", job.JobID, fErr)
+		return
+	}
+	log.Printf("jobs: %s %s attempt %d/%d failed (%s): %v", job.Type, job.JobID, job.Attempts, job.MaxAttempts, status, err)
+}
+
+// heartbeat extends the lease until ctx ends, cancelling the task if the lease is lost
+func (r *Runner) heartbeat(ctx context.Context, jobID uuid.UUID, cancelTask context.CancelFunc) {
+	ticker := time.NewTicker(r.opts.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.models.HeartbeatJob(ctx, jobID, r.workerID, r.opts.Lease)
+			if errors.Is(err, db.ErrLeaseLost) {
+				log.Printf("jobs: lost lease on %s, abandoning it", jobID)
+				cancelTask()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("jobs: heartbeat for %s failed: %v", jobID, err)
+			}
+		}
+	}
+}
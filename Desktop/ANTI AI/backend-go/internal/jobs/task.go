@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gaply-backend/backend-go/internal/db"
+)
+
+// Task is a job claimed by this process
+type Task struct {
+	*db.Job
+	runner *Runner
+}
+
+// Decode unmarshals the job payload into v
+func (t *Task) Decode(v interface{}) error {
+	if err := json.Unmarshal(t.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s payload: %w", t.Type, err))
+	}
+	return nil
+}
+
+// Progress records progress and an optional interim result on the job
+func (t *Task) Progress(ctx context.Context, percent int, result interface{}) error {
+	var data json.RawMessage
+	if result != nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		data = encoded
+	}
+	return t.runner.models.UpdateJobProgress(ctx, t.JobID, t.runner.workerID, percent, data)
+}
+
+// LastAttempt reports whether a failure now would dead-letter the job
+func (t *Task) LastAttempt() bool {
+	return t.Attempts >= t.MaxAttempts
+}
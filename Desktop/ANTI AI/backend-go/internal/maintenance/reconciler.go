@@ -0,0 +1,237 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gaply-backend/backend-go/internal/db"
+	"gaply-backend/backend-go/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// listPageSize is how many storage objects are checked per database round trip
+const listPageSize = 500
+
+// Options configures a Reconciler
+type Options struct {
+	// Interval between scheduled runs; zero disables scheduling
+	Interval time.Duration
+	// OrphanGrace is how long an unreferenced object must go unmodified before
+	// it is deleted; it should be at least the upload finalize window
+	OrphanGrace time.Duration
+	// StuckAfter is how long a paper may sit in pending or processing with no
+	// live ingest job before it is marked failed
+	StuckAfter time.Duration
+	// DryRun makes scheduled runs report without changing anything
+	DryRun bool
+}
+
+// Report describes what a run found and, unless it was a dry run, changed
+type Report struct {
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	ObjectsScanned  int      `json:"objects_scanned"`
+	OrphanedObjects []string `json:"orphaned_objects"`
+	DeletedObjects  int      `json:"deleted_objects"`
+
+	StuckPapers  []string `json:"stuck_papers"`
+	FailedPapers int64    `json:"failed_papers"`
+
+	ExpiredCacheRows int64 `json:"expired_cache_rows"`
+	PurgedCacheRows  int64 `json:"purged_cache_rows"`
+
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Reconciler cleans up what failed uploads and ingests leave behind: storage
+// objects no paper references, papers stuck mid-ingest and expired API cache rows
+type Reconciler struct {
+	models  *db.Models
+	storage storage.Backend
+	opts    Options
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// NewReconciler creates a new Reconciler
+func NewReconciler(models *db.Models, store storage.Backend, opts Options) *Reconciler {
+	if opts.OrphanGrace <= 0 {
+		opts.OrphanGrace = 24 * time.Hour
+	}
+	if opts.StuckAfter <= 0 {
+		opts.StuckAfter = 6 * time.Hour
+	}
+
+	return &Reconciler{
+		models:  models,
+		storage: store,
+		opts:    opts,
+	}
+}
+
+// Start runs the reconciler every Interval until ctx is cancelled
+func (r *Reconciler) Start(ctx context.Context) {
+	if r.opts.Interval <= 0 {
+		log.Printf("maintenance: scheduled reconcile disabled")
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := r.Run(ctx, r.opts.DryRun)
+				log.Printf("maintenance: %s", report.Summary())
+			}
+		}
+	}()
+	log.Printf("maintenance: reconciling every %s (dry run: %t)", r.opts.Interval, r.opts.DryRun)
+}
+
+// Wait blocks until the scheduled loop has stopped
+func (r *Reconciler) Wait() {
+	r.wg.Wait()
+}
+
+// Run performs one reconcile pass. Each step runs even if an earlier one
+// failed; failures are collected in the report.
+func (r *Reconciler) Run(ctx context.Context, dryRun bool) *Report {
+	// Overlapping passes would race each other's deletes
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		DryRun:          dryRun,
+		StartedAt:       time.Now(),
+		OrphanedObjects: []string{},
+		StuckPapers:     []string{},
+	}
+
+	if err := r.collectOrphans(ctx, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("orphaned objects: %v", err))
+	}
+	if err := r.failStuckPapers(ctx, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("stuck papers: %v", err))
+	}
+	if err := r.purgeCache(ctx, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("api cache: %v", err))
+	}
+
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// collectOrphans finds storage objects unmodified for the grace period that no
+// paper references and no pending upload was issued for, deleting them unless
+// this is a dry run
+func (r *Reconciler) collectOrphans(ctx context.Context, report *Report) error {
+	cutoff := report.StartedAt.Add(-r.opts.OrphanGrace)
+
+	cursor := ""
+	for {
+		page, err := r.storage.ListFiles(ctx, "", storage.ListOptions{Limit: listPageSize, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		report.ObjectsScanned += len(page.Files)
+
+		// Objects with an unknown age are never old enough to delete
+		var candidates []string
+		for _, file := range page.Files {
+			if !file.ModifiedAt.IsZero() && file.ModifiedAt.Before(cutoff) {
+				candidates = append(candidates, file.Path)
+			}
+		}
+
+		if len(candidates) > 0 {
+			referenced, err := r.models.ReferencedStoragePaths(ctx, candidates)
+			if err != nil {
+				return err
+			}
+			pending, err := r.models.PendingUploadPaths(ctx, candidates)
+			if err != nil {
+				return err
+			}
+
+			for _, objectPath := range candidates {
+				if referenced[objectPath] || pending[objectPath] {
+					continue
+				}
+				report.OrphanedObjects = append(report.OrphanedObjects, objectPath)
+				if report.DryRun {
+					continue
+				}
+				if err := r.storage.DeleteFile(ctx, objectPath); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("delete %s: %v", objectPath, err))
+					continue
+				}
+				report.DeletedObjects++
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// failStuckPapers marks papers stuck mid-ingest as failed unless this is a dry run
+func (r *Reconciler) failStuckPapers(ctx context.Context, report *Report) error {
+	cutoff := report.StartedAt.Add(-r.opts.StuckAfter)
+
+	papers, err := r.models.ListStuckPapers(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	if len(papers) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(papers))
+	for _, paper := range papers {
+		ids = append(ids, paper.ID)
+		report.StuckPapers = append(report.StuckPapers, paper.ID.String())
+	}
+
+	if report.DryRun {
+		return nil
+	}
+
+	report.FailedPapers, err = r.models.FailStuckPapers(ctx, ids, cutoff)
+	return err
+}
+
+// purgeCache deletes expired api_cache rows unless this is a dry run
+func (r *Reconciler) purgeCache(ctx context.Context, report *Report) error {
+	var err error
+	report.ExpiredCacheRows, err = r.models.CountExpiredCache(ctx, report.StartedAt)
+	if err != nil || report.DryRun {
+		return err
+	}
+
+	report.PurgedCacheRows, err = r.models.PurgeExpiredCache(ctx, report.StartedAt)
+	return err
+}
+
+// Summary is a one-line description of the report for logs
+func (rep *Report) Summary() string {
+	return fmt.Sprintf("dry_run=%t objects=%d orphaned=%d deleted=%d stuck=%d failed=%d expired_cache=%d purged_cache=%d errors=%d",
+		rep.DryRun, rep.ObjectsScanned, len(rep.OrphanedObjects), rep.DeletedObjects,
+		len(rep.StuckPapers), rep.FailedPapers, rep.ExpiredCacheRows, rep.PurgedCacheRows, len(rep.Errors))
+}
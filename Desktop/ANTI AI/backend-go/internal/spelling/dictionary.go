@@ -0,0 +1,166 @@
+package spelling
+
+// SymSpell limits: candidates are at most maxEditDistance edits away, and
+// only the first prefixLength letters of each word are indexed
+const (
+	maxEditDistance = 2
+	prefixLength    = 7
+	// minSplitLength is the shortest run-together word worth splitting
+	minSplitLength = 5
+)
+
+// dictionary is a SymSpell index: every word is stored under each string
+// reachable by deleting up to maxEditDistance letters from its prefix, so a
+// lookup only has to generate deletes of the query word instead of all edits
+type dictionary struct {
+	words   map[string]int
+	deletes map[string][]string
+}
+
+func newDictionary(words map[string]int) *dictionary {
+	d := &dictionary{
+		words:   words,
+		deletes: make(map[string][]string),
+	}
+	for word := range words {
+		for key := range deletesOf(prefix([]rune(word)), maxEditDistance) {
+			d.deletes[key] = append(d.deletes[key], word)
+		}
+	}
+	return d
+}
+
+// lookup finds the closest known word, preferring fewer edits and then more
+// frequent words. Short words get a smaller edit budget so "cat" does not
+// become "art".
+func (d *dictionary) lookup(word string) (string, bool) {
+	runes := []rune(word)
+	budget := editBudget(len(runes))
+	if budget == 0 {
+		return "", false
+	}
+
+	best, bestDistance, bestCount := "", budget+1, 0
+	seen := make(map[string]bool)
+	for key := range deletesOf(prefix(runes), budget) {
+		for _, candidate := range d.deletes[key] {
+			if seen[candidate] {
+				continue
+			}
+			seen[candidate] = true
+
+			c := []rune(candidate)
+			if abs(len(c)-len(runes)) > budget {
+				continue
+			}
+			distance := editDistance(runes, c)
+			if distance > budget {
+				continue
+			}
+			count := d.words[candidate]
+			if distance < bestDistance || (distance == bestDistance && count > bestCount) {
+				best, bestDistance, bestCount = candidate, distance, count
+			}
+		}
+	}
+
+	return best, best != ""
+}
+
+// split divides a run-together word into the two known words whose rarer
+// half is most frequent
+func (d *dictionary) split(word string) (string, string, bool) {
+	runes := []rune(word)
+	if len(runes) < minSplitLength {
+		return "", "", false
+	}
+
+	var left, right string
+	bestCount := 0
+	for i := 2; i <= len(runes)-2; i++ {
+		l, r := string(runes[:i]), string(runes[i:])
+		count := min(d.words[l], d.words[r])
+		if count > bestCount {
+			left, right, bestCount = l, r, count
+		}
+	}
+
+	return left, right, bestCount > 0
+}
+
+// editBudget is how many edits a word of n letters may be corrected by
+func editBudget(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n <= 4:
+		return 1
+	default:
+		return maxEditDistance
+	}
+}
+
+// prefix truncates a word to the indexed prefix length
+func prefix(word []rune) string {
+	if len(word) > prefixLength {
+		word = word[:prefixLength]
+	}
+	return string(word)
+}
+
+// deletesOf returns word and every string formed by deleting up to distance
+// letters from it
+func deletesOf(word string, distance int) map[string]bool {
+	result := map[string]bool{word: true}
+	frontier := []string{word}
+	for ; distance > 0; distance-- {
+		var next []string
+		for _, w := range frontier {
+			runes := []rune(w)
+			for i := range runes {
+				deleted := string(runes[:i]) + string(runes[i+1:])
+				if !result[deleted] {
+					result[deleted] = true
+					next = append(next, deleted)
+				}
+			}
+		}
+		frontier = next
+	}
+	return result
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions each cost one
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
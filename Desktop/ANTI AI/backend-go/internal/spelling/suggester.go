@@ -0,0 +1,170 @@
+package spelling
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gaply-backend/backend-go/internal/db"
+)
+
+// Options configures a Suggester
+type Options struct {
+	// RefreshInterval between vocabulary reloads; zero loads once at start
+	RefreshInterval time.Duration
+	// MinCount is how often a word must occur to be trusted and suggested
+	MinCount int
+	// MaxWords caps the vocabulary at the most frequent words
+	MaxWords int
+}
+
+// Suggester proposes corrections for misspelled query words using a
+// vocabulary learned from the papers and search results we hold
+type Suggester struct {
+	models *db.Models
+	opts   Options
+
+	mu   sync.RWMutex
+	dict *dictionary
+
+	wg sync.WaitGroup
+}
+
+// NewSuggester creates a new Suggester with an empty vocabulary; call Start
+// or Refresh to load it
+func NewSuggester(models *db.Models, opts Options) *Suggester {
+	if opts.MinCount <= 0 {
+		opts.MinCount = 3
+	}
+	if opts.MaxWords <= 0 {
+		opts.MaxWords = 100000
+	}
+
+	return &Suggester{
+		models: models,
+		opts:   opts,
+		dict:   newDictionary(nil),
+	}
+}
+
+// Start loads the vocabulary and reloads it every RefreshInterval until ctx
+// is cancelled
+func (s *Suggester) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		s.refreshAndLog(ctx)
+		if s.opts.RefreshInterval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(s.opts.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshAndLog(ctx)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the refresh loop has stopped
+func (s *Suggester) Wait() {
+	s.wg.Wait()
+}
+
+// Refresh rebuilds the vocabulary from the database. The previous vocabulary
+// keeps serving lookups until the new one is ready.
+func (s *Suggester) Refresh(ctx context.Context) (int, error) {
+	words, err := s.models.WordFrequencies(ctx, s.opts.MinCount, s.opts.MaxWords)
+	if err != nil {
+		return 0, err
+	}
+
+	dict := newDictionary(words)
+
+	s.mu.Lock()
+	s.dict = dict
+	s.mu.Unlock()
+
+	return len(words), nil
+}
+
+func (s *Suggester) refreshAndLog(ctx context.Context) {
+	n, err := s.Refresh(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("spelling: vocabulary refresh failed: %v", err)
+		}
+		return
+	}
+	log.Printf("spelling: loaded %d words", n)
+}
+
+// Suggest returns the query with misspelled words corrected, or false when
+// every word is known or nothing better was found. Words run together, such
+// as "deeplearning", are split when both halves are known.
+func (s *Suggester) Suggest(query string) (string, bool) {
+	s.mu.RLock()
+	dict := s.dict
+	s.mu.RUnlock()
+
+	if len(dict.words) == 0 {
+		return "", false
+	}
+
+	fields := strings.Fields(query)
+	changed := false
+	for i, field := range fields {
+		word := strings.ToLower(field)
+		if !isWord(word) || dict.words[word] > 0 {
+			continue
+		}
+
+		if correction, ok := dict.lookup(word); ok {
+			fields[i] = matchCase(field, correction)
+			changed = true
+		} else if left, right, ok := dict.split(word); ok {
+			fields[i] = matchCase(field, left) + " " + matchCase(field, right)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return "", false
+	}
+	return strings.Join(fields, " "), true
+}
+
+// isWord reports whether a query token is plain letters, the only kind of
+// token worth correcting
+func isWord(token string) bool {
+	for _, r := range token {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return token != ""
+}
+
+// matchCase gives a correction the capitalization of the word it replaces:
+// all caps for acronyms like "CRISPR", a leading capital, or lower case
+func matchCase(original, correction string) string {
+	if strings.ToUpper(original) == original {
+		return strings.ToUpper(correction)
+	}
+	if r := []rune(original); unicode.IsUpper(r[0]) {
+		c := []rune(correction)
+		c[0] = unicode.ToUpper(c[0])
+		return string(c)
+	}
+	return correction
+}
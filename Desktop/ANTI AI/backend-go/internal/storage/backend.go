@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gaply-backend/backend-go/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// Storage backends selectable through config.Config.StorageBackend
+const (
+	BackendSupabase = "supabase"
+	BackendLocal    = "local"
+)
+
+// ErrNotFound is returned when a storage object does not exist
+var ErrNotFound = errors.New("storage object not found")
+
+// Backend stores paper PDFs. Paths are slash-separated and relative to the bucket.
+type Backend interface {
+	// UploadFile stores file at its content-addressed path and returns that path
+	UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error)
+	// GetSignedURL returns a URL a client can upload the object at filepath to directly
+	GetSignedURL(ctx context.Context, filepath string, contentType string, expiresIn time.Duration) (*SignedUpload, error)
+	// GetDownloadURL returns a time-limited URL for reading an object
+	GetDownloadURL(ctx context.Context, filepath string, expiresIn time.Duration) (string, error)
+	DeleteFile(ctx context.Context, filepath string) error
+	// MoveFile renames an object, replacing nothing: the destination must not exist
+	MoveFile(ctx context.Context, from, to string) error
+	FileExists(ctx context.Context, filepath string) (bool, error)
+	GetFileInfo(ctx context.Context, filepath string) (*FileInfo, error)
+	// ListFiles pages through objects whose path starts with prefix, in path order
+	ListFiles(ctx context.Context, prefix string, opts ListOptions) (*FileList, error)
+	// Open streams an object's content
+	Open(ctx context.Context, filepath string) (io.ReadCloser, error)
+	// ReadHead reads up to n bytes from the start of an object
+	ReadHead(ctx context.Context, filepath string, n int) ([]byte, error)
+}
+
+// SignedUpload is a signed upload URL together with the object path it writes to
+type SignedUpload struct {
+	URL       string    `json:"upload_url"`
+	Path      string    `json:"path"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileInfo represents file metadata. Checksum is the backend's content
+// digest (the ETag for Supabase, hex SHA-256 for local storage) and may be
+// empty in listings.
+type FileInfo struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Checksum    string    `json:"checksum,omitempty"`
+	ModifiedAt  time.Time `json:"modified_at"`
+}
+
+// Listing page sizes
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// ListOptions pages a listing. Cursor is the NextCursor of the previous page.
+type ListOptions struct {
+	Limit  int
+	Cursor string
+}
+
+// limit returns the page size, defaulted and capped
+func (o ListOptions) limit() int {
+	switch {
+	case o.Limit <= 0:
+		return defaultListLimit
+	case o.Limit > maxListLimit:
+		return maxListLimit
+	default:
+		return o.Limit
+	}
+}
+
+// FileList is one page of a listing
+type FileList struct {
+	Files      []FileInfo `json:"files"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// pageFiles trims files (fetched with one extra entry) to a page
+func pageFiles(files []FileInfo, limit int) *FileList {
+	page := &FileList{Files: files}
+	if len(files) > limit {
+		page.Files = files[:limit]
+		page.NextCursor = files[limit-1].Path
+	}
+	if page.Files == nil {
+		page.Files = []FileInfo{}
+	}
+	return page
+}
+
+// New creates the storage backend selected in cfg
+func New(cfg *config.Config) (Backend, error) {
+	switch cfg.StorageBackend {
+	case BackendSupabase:
+		return NewSupabaseClient(cfg.SupabaseURL, cfg.SupabaseServiceKey)
+	case BackendLocal:
+		return NewLocalBackend(cfg.LocalStorageDir, cfg.PublicURL, cfg.StorageSigningSecret)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// Prefixes of the object paths handed out for uploads
+const (
+	// UploadPrefix holds uploads whose content is not known yet
+	UploadPrefix = "uploads/"
+	// ContentPrefix holds PDFs stored by their SHA-256 digest
+	ContentPrefix = "sha256/"
+)
+
+// NewObjectPath generates a unique uploads/YYYY/MM/uuid.ext path for an upload
+// whose content is not known yet
+func NewObjectPath(filename string) string {
+	ext := getFileExtension(filename)
+	return fmt.Sprintf("%s%s/%s%s", UploadPrefix, time.Now().Format("2006/01"), uuid.New().String(), ext)
+}
+
+// ContentPath returns the content-addressed path of a PDF with the given hex
+// SHA-256 digest, fanned out by its first two bytes
+func ContentPath(sum string) string {
+	return fmt.Sprintf("%s%s/%s/%s.pdf", ContentPrefix, sum[:2], sum[2:4], sum)
+}
+
+// IsUploadPath reports whether objectPath has the shape of a path issued for
+// an upload, under UploadPrefix or ContentPrefix
+func IsUploadPath(objectPath string) bool {
+	if strings.Contains(objectPath, "..") {
+		return false
+	}
+	return strings.HasPrefix(objectPath, UploadPrefix) || strings.HasPrefix(objectPath, ContentPrefix)
+}
+
+// ValidSHA256 reports whether sum is a lowercase hex SHA-256 digest
+func ValidSHA256(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil && strings.ToLower(sum) == sum
+}
+
+// HashObject streams an object and returns its hex SHA-256 digest
+func HashObject(ctx context.Context, b Backend, filepath string) (string, error) {
+	body, err := b.Open(ctx, filepath)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uploadContent buffers file to hash it and stores it with put at its
+// content path, unless an object is already there
+func uploadContent(ctx context.Context, b Backend, file io.Reader, put func(objectPath string, data []byte) error) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	objectPath := ContentPath(hex.EncodeToString(sum[:]))
+
+	exists, err := b.FileExists(ctx, objectPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return objectPath, nil
+	}
+
+	if err := put(objectPath, data); err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return objectPath, nil
+}
+
+// getFileExtension extracts the file extension from a filename
+func getFileExtension(filename string) string {
+	for i := len(filename) - 1; i >= 0; i-- {
+		if filename[i] == '.' {
+			return filename[i:]
+		}
+	}
+	return ""
+}
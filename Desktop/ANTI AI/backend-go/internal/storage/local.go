@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations a local signed URL can be issued for
+const (
+	LocalOpUpload   = "upload"
+	LocalOpDownload = "download"
+)
+
+// LocalRoutePrefix is where the API serves local storage objects
+const LocalRoutePrefix = "/storage/local/"
+
+// ErrInvalidToken is returned when a local signed URL does not verify
+var ErrInvalidToken = errors.New("invalid storage token")
+
+// ErrTokenExpired is returned when a local signed URL is past its expiry
+var ErrTokenExpired = errors.New("storage token expired")
+
+// LocalBackend stores objects on the local filesystem. Signed URLs point back
+// at the API, which checks their HMAC token before reading or writing.
+type LocalBackend struct {
+	root      string
+	publicURL string
+	secret    []byte
+}
+
+var _ Backend = (*LocalBackend)(nil)
+
+// NewLocalBackend creates a local storage backend rooted at dir
+func NewLocalBackend(dir, publicURL, secret string) (*LocalBackend, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("a signing secret is required for local storage")
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalBackend{
+		root:      root,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		secret:    []byte(secret),
+	}, nil
+}
+
+// UploadFile writes a file at its content-addressed path, skipping the write
+// when an identical file is already stored
+func (l *LocalBackend) UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
+	return uploadContent(ctx, l, file, func(objectPath string, data []byte) error {
+		return l.Write(objectPath, bytes.NewReader(data))
+	})
+}
+
+// GetSignedURL returns an API URL the client can PUT the object at objectPath to
+func (l *LocalBackend) GetSignedURL(ctx context.Context, objectPath string, contentType string, expiresIn time.Duration) (*SignedUpload, error) {
+	expires := time.Now().Add(expiresIn)
+	token := l.sign(LocalOpUpload, objectPath, expires.Unix())
+
+	return &SignedUpload{
+		URL:       l.signedURL(LocalOpUpload, objectPath, expires.Unix(), token),
+		Path:      objectPath,
+		Token:     token,
+		ExpiresAt: expires,
+	}, nil
+}
+
+// GetDownloadURL returns an API URL the object can be fetched from until it expires
+func (l *LocalBackend) GetDownloadURL(ctx context.Context, objectPath string, expiresIn time.Duration) (string, error) {
+	expires := time.Now().Add(expiresIn).Unix()
+	return l.signedURL(LocalOpDownload, objectPath, expires, l.sign(LocalOpDownload, objectPath, expires)), nil
+}
+
+// DeleteFile removes an object; deleting a missing object is not an error
+func (l *LocalBackend) DeleteFile(ctx context.Context, objectPath string) error {
+	full, err := l.resolve(objectPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// MoveFile renames an object, failing if the destination exists
+func (l *LocalBackend) MoveFile(ctx context.Context, from, to string) error {
+	src, err := l.resolve(from)
+	if err != nil {
+		return err
+	}
+	dst, err := l.resolve(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(src); errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("failed to move file: %s already exists", to)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+	return nil
+}
+
+// FileExists checks if an object exists
+func (l *LocalBackend) FileExists(ctx context.Context, objectPath string) (bool, error) {
+	full, err := l.resolve(objectPath)
+	if err != nil {
+		return false, err
+	}
+
+	stat, err := os.Stat(full)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !stat.IsDir(), nil
+}
+
+// GetFileInfo gets metadata about an object, hashing its content for the checksum
+func (l *LocalBackend) GetFileInfo(ctx context.Context, objectPath string) (*FileInfo, error) {
+	return l.fileInfo(objectPath, true)
+}
+
+// ListFiles pages through objects under prefix in path order. Listed entries
+// carry no checksum so large listings don't read every file in full.
+func (l *LocalBackend) ListFiles(ctx context.Context, prefix string, opts ListOptions) (*FileList, error) {
+	var paths []string
+	err := filepath.WalkDir(l.root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip directories and uploads still being written
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, prefix) && rel > opts.Cursor {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	sort.Strings(paths)
+	limit := opts.limit()
+	if len(paths) > limit+1 {
+		paths = paths[:limit+1]
+	}
+
+	files := make([]FileInfo, 0, len(paths))
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := l.fileInfo(p, false)
+		if err != nil {
+			// Deleted between the walk and now
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		files = append(files, *info)
+	}
+
+	return pageFiles(files, limit), nil
+}
+
+// fileInfo stats an object and sniffs its content type, optionally hashing it
+func (l *LocalBackend) fileInfo(objectPath string, checksum bool) (*FileInfo, error) {
+	file, err := l.openFile(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	hash := sha256.New()
+	head, err := io.ReadAll(io.TeeReader(io.LimitReader(file, 512), hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	info := &FileInfo{
+		Path:        objectPath,
+		Size:        stat.Size(),
+		ContentType: http.DetectContentType(head),
+		ModifiedAt:  stat.ModTime(),
+	}
+
+	if checksum {
+		if _, err := io.Copy(hash, file); err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		info.Checksum = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return info, nil
+}
+
+// ReadHead reads up to n bytes from the start of an object
+func (l *LocalBackend) ReadHead(ctx context.Context, objectPath string, n int) ([]byte, error) {
+	file, err := l.openFile(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head, err := io.ReadAll(io.LimitReader(file, int64(n)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return head, nil
+}
+
+// Open opens an object for reading
+func (l *LocalBackend) Open(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	return l.openFile(objectPath)
+}
+
+// openFile opens the file backing an object
+func (l *LocalBackend) openFile(objectPath string) (*os.File, error) {
+	full, err := l.resolve(objectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(full)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Write stores r at objectPath, replacing the object atomically
+func (l *LocalBackend) Write(objectPath string, r io.Reader) error {
+	full, err := l.resolve(objectPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), full); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
+
+// Verify checks a signed URL's token for an operation on objectPath
+func (l *LocalBackend) Verify(op, objectPath string, expires int64, token string) error {
+	expected := l.sign(op, objectPath, expires)
+	if !hmac.Equal([]byte(expected), []byte(token)) {
+		return ErrInvalidToken
+	}
+	if time.Now().Unix() > expires {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// sign returns the HMAC token binding an operation, path and expiry
+func (l *LocalBackend) sign(op, objectPath string, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "%s:%s:%d", op, objectPath, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedURL builds the API URL for a signed operation
+func (l *LocalBackend) signedURL(op, objectPath string, expires int64, token string) string {
+	query := url.Values{}
+	query.Set("op", op)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("token", token)
+
+	escaped := (&url.URL{Path: objectPath}).EscapedPath()
+	return l.publicURL + LocalRoutePrefix + escaped + "?" + query.Encode()
+}
+
+// resolve maps an object path to a file under the storage root,
+// rejecting paths that would escape it
+func (l *LocalBackend) resolve(objectPath string) (string, error) {
+	cleaned := path.Clean("/" + objectPath)
+	if cleaned == "/" || cleaned != "/"+objectPath {
+		return "", fmt.Errorf("invalid storage path %q", objectPath)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned[1:])), nil
+}
@@ -1,23 +1,41 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	storage_go "github.com/supabase-community/storage-go"
 	supabase "github.com/supabase-community/supabase-go"
 )
 
+// signedUploadTTL is how long Supabase keeps signed upload URLs valid; the
+// lifetime is fixed server-side and cannot be requested per URL
+const signedUploadTTL = 2 * time.Hour
+
 // SupabaseClient handles Supabase storage operations
 type SupabaseClient struct {
 	client        *supabase.Client
 	storageClient *storage_go.Client
+	storageURL    string
+	serviceKey    string
+	httpClient    *http.Client
 	bucket        string
 }
 
+// listPageSize is how many entries are requested per storage list call
+const listPageSize = 100
+
+var _ Backend = (*SupabaseClient)(nil)
+
 // NewSupabaseClient creates a new Supabase storage client
 func NewSupabaseClient(url, serviceKey string) (*SupabaseClient, error) {
 	client, err := supabase.NewClient(url, serviceKey, nil)
@@ -25,44 +43,62 @@ func NewSupabaseClient(url, serviceKey string) (*SupabaseClient, error) {
 		return nil, fmt.Errorf("failed to create Supabase client: %w", err)
 	}
 
-	// Create storage client
-	storageClient := storage_go.NewClient(url, serviceKey, nil)
+	// Create storage client; the storage API lives under /storage/v1 of the project URL
+	storageURL := strings.TrimRight(url, "/") + "/storage/v1"
+	storageClient := storage_go.NewClient(storageURL, serviceKey, nil)
 
 	return &SupabaseClient{
 		client:        client,
 		storageClient: storageClient,
+		storageURL:    storageURL,
+		serviceKey:    serviceKey,
+		httpClient:    &http.Client{Timeout: 2 * time.Minute},
 		bucket:        "papers", // Default bucket name
 	}, nil
 }
 
-// UploadFile uploads a file to Supabase storage
+// UploadFile stores a file at its content-addressed path, skipping the upload
+// when an identical file is already stored
 func (s *SupabaseClient) UploadFile(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
-	// Generate unique filename
-	ext := getFileExtension(filename)
-	uniqueName := fmt.Sprintf("%s/%s%s", time.Now().Format("2006/01"), uuid.New().String(), ext)
+	return uploadContent(ctx, s, file, func(objectPath string, data []byte) error {
+		_, err := s.storageClient.UploadFile(s.bucket, objectPath, bytes.NewReader(data), storage_go.FileOptions{
+			ContentType: &contentType,
+		})
+		return err
+	})
+}
 
-	// Upload file
-	_, err := s.storageClient.UploadFile(s.bucket, uniqueName, file)
+// GetSignedURL generates a signed URL for uploading to filepath.
+// Supabase fixes the URL lifetime, so expiresIn only caps the reported expiry.
+func (s *SupabaseClient) GetSignedURL(ctx context.Context, filepath string, contentType string, expiresIn time.Duration) (*SignedUpload, error) {
+	// Create signed URL for upload
+	response, err := s.storageClient.CreateSignedUploadUrl(s.bucket, filepath)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %w", err)
+		return nil, fmt.Errorf("failed to create signed upload URL: %w", err)
 	}
 
-	return uniqueName, nil
-}
+	// The storage API answers with a URL relative to its base
+	signed := response.Url
+	if !strings.HasPrefix(signed, "http") {
+		signed = s.storageURL + "/" + strings.TrimPrefix(signed, "/")
+	}
 
-// GetSignedURL generates a signed URL for file upload
-func (s *SupabaseClient) GetSignedURL(ctx context.Context, filename string, contentType string, expiresIn time.Duration) (string, error) {
-	// Generate unique filename
-	ext := getFileExtension(filename)
-	uniqueName := fmt.Sprintf("%s/%s%s", time.Now().Format("2006/01"), uuid.New().String(), ext)
+	var token string
+	if parsed, err := url.Parse(signed); err == nil {
+		token = parsed.Query().Get("token")
+	}
 
-	// Create signed URL for upload
-	response, err := s.storageClient.CreateSignedUploadUrl(s.bucket, uniqueName)
-	if err != nil {
-		return "", fmt.Errorf("failed to create signed upload URL: %w", err)
+	ttl := signedUploadTTL
+	if expiresIn > 0 && expiresIn < ttl {
+		ttl = expiresIn
 	}
 
-	return response.Url, nil
+	return &SignedUpload{
+		URL:       signed,
+		Path:      filepath,
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
 }
 
 // GetDownloadURL generates a signed download URL
@@ -85,12 +121,24 @@ func (s *SupabaseClient) DeleteFile(ctx context.Context, filepath string) error
 	return nil
 }
 
+// MoveFile renames a file within the bucket
+func (s *SupabaseClient) MoveFile(ctx context.Context, from, to string) error {
+	_, err := s.storageClient.MoveFile(s.bucket, from, to)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return nil
+}
+
 // FileExists checks if a file exists in storage
 func (s *SupabaseClient) FileExists(ctx context.Context, filepath string) (bool, error) {
-	_, err := s.storageClient.DownloadFile(s.bucket, filepath)
+	_, err := s.GetFileInfo(ctx, filepath)
 	if err != nil {
-		// Check if it's a "not found" error
-		if err.Error() == "file not found" {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -99,46 +147,241 @@ func (s *SupabaseClient) FileExists(ctx context.Context, filepath string) (bool,
 	return true, nil
 }
 
-// GetFileInfo gets metadata about a file
+// Open streams a file's content
+func (s *SupabaseClient) Open(ctx context.Context, filepath string) (io.ReadCloser, error) {
+	resp, err := s.objectRequest(ctx, http.MethodGet, filepath, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ReadHead reads up to n bytes from the start of a file without downloading
+// the rest of it, e.g. to sniff its type
+func (s *SupabaseClient) ReadHead(ctx context.Context, filepath string, n int) ([]byte, error) {
+	resp, err := s.objectRequest(ctx, http.MethodGet, filepath, http.Header{
+		"Range": {fmt.Sprintf("bytes=0-%d", n-1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return head, nil
+}
+
+// objectRequest sends an authenticated request for an object and returns the
+// response if it succeeded; the caller closes its body
+func (s *SupabaseClient) objectRequest(ctx context.Context, method, filepath string, header http.Header) (*http.Response, error) {
+	objectURL := s.storageURL + "/object/authenticated/" + s.bucket + "/" + (&url.URL{Path: filepath}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, method, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+s.serviceKey)
+	req.Header.Set("apikey", s.serviceKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	// Some storage API versions answer 400 for a missing object, but say so in the body
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+	if resp.StatusCode == http.StatusNotFound || isNotFoundBody(body) {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("storage returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+}
+
+// errorBodyLimit caps how much of a storage error response is read
+const errorBodyLimit = 4 << 10
+
+// isNotFoundBody reports whether a storage API error body describes a missing
+// object, e.g. {"statusCode":"404","error":"not_found","message":"Object not found"}
+func isNotFoundBody(body []byte) bool {
+	var apiErr struct {
+		StatusCode json.RawMessage `json:"statusCode"`
+		Error      string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return false
+	}
+	return strings.Trim(string(apiErr.StatusCode), `"`) == "404" || apiErr.Error == "not_found"
+}
+
+// isNotFound reports whether a storage API error means the object is missing.
+// The API sends statusCode as a string, so the message is checked as well.
+func isNotFound(err error) bool {
+	var storageErr *storage_go.StorageError
+	if !errors.As(err, &storageErr) {
+		return false
+	}
+	return storageErr.Status == http.StatusNotFound ||
+		strings.Contains(strings.ToLower(storageErr.Message), "not found")
+}
+
+// GetFileInfo gets metadata about a file from the storage API's object headers
 func (s *SupabaseClient) GetFileInfo(ctx context.Context, filepath string) (*FileInfo, error) {
-	// This would require a custom implementation as Supabase Go client doesn't expose file info directly
-	// For now, we'll return basic info
-	return &FileInfo{
+	resp, err := s.objectRequest(ctx, http.MethodHead, filepath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &FileInfo{
 		Path:        filepath,
-		Size:        0,                 // Would need to implement size checking
-		ContentType: "application/pdf", // Assume PDF for papers
-		CreatedAt:   time.Now(),
-	}, nil
-}
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		Checksum:    strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModifiedAt = modified
+	}
 
-// FileInfo represents file metadata
-type FileInfo struct {
-	Path        string    `json:"path"`
-	Size        int64     `json:"size"`
-	ContentType string    `json:"content_type"`
-	CreatedAt   time.Time `json:"created_at"`
+	return info, nil
 }
 
-// getFileExtension extracts the file extension from a filename
-func getFileExtension(filename string) string {
-	for i := len(filename) - 1; i >= 0; i-- {
-		if filename[i] == '.' {
-			return filename[i:]
+// CreateBucket creates a private PDF bucket, succeeding if it already exists
+func (s *SupabaseClient) CreateBucket(ctx context.Context, bucketName string) error {
+	_, err := s.storageClient.CreateBucket(bucketName, storage_go.BucketOptions{
+		Public:           false,
+		AllowedMimeTypes: []string{"application/pdf"},
+	})
+	if err != nil {
+		var storageErr *storage_go.StorageError
+		if errors.As(err, &storageErr) && (storageErr.Status == http.StatusConflict ||
+			strings.Contains(strings.ToLower(storageErr.Message), "already exists")) {
+			return nil
 		}
+		return fmt.Errorf("failed to create bucket: %w", err)
 	}
-	return ""
+
+	return nil
 }
 
-// CreateBucket creates a new storage bucket if it doesn't exist
-func (s *SupabaseClient) CreateBucket(ctx context.Context, bucketName string) error {
-	// This would require admin privileges and custom implementation
-	// For now, we'll assume the bucket exists
+// ListFiles pages through files whose path starts with prefix. The storage API
+// lists one folder at a time, so folders are walked depth first in path order.
+func (s *SupabaseClient) ListFiles(ctx context.Context, prefix string, opts ListOptions) (*FileList, error) {
+	folder := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		folder = prefix[:i]
+	}
+
+	limit := opts.limit()
+	var files []FileInfo
+	if err := s.listFolder(ctx, folder, prefix, opts.Cursor, limit+1, &files); err != nil {
+		return nil, err
+	}
+
+	return pageFiles(files, limit), nil
+}
+
+// listEntry is a folder listing entry keyed for path ordering
+type listEntry struct {
+	key    string
+	folder bool
+	object storage_go.FileObject
+}
+
+// listFolder appends files under folder that match prefix and sort after
+// cursor to out, until out holds want entries
+func (s *SupabaseClient) listFolder(ctx context.Context, folder, prefix, cursor string, want int, out *[]FileInfo) error {
+	var entries []listEntry
+	for offset := 0; ; offset += listPageSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		objects, err := s.storageClient.ListFiles(s.bucket, folder, storage_go.FileSearchOptions{
+			Limit:  listPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+
+		for _, object := range objects {
+			full := object.Name
+			if folder != "" {
+				full = folder + "/" + object.Name
+			}
+
+			// Folders have no object ID; key them with the trailing slash
+			// so they sort exactly where their contents do
+			if object.Id == "" {
+				key := full + "/"
+				if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+					continue
+				}
+				if key < cursor && !strings.HasPrefix(cursor, key) {
+					continue
+				}
+				entries = append(entries, listEntry{key: key, folder: true, object: object})
+				continue
+			}
+
+			if strings.HasPrefix(full, prefix) && full > cursor {
+				entries = append(entries, listEntry{key: full, object: object})
+			}
+		}
+
+		if len(objects) < listPageSize {
+			break
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	for _, entry := range entries {
+		if len(*out) >= want {
+			return nil
+		}
+		if entry.folder {
+			if err := s.listFolder(ctx, strings.TrimSuffix(entry.key, "/"), prefix, cursor, want, out); err != nil {
+				return err
+			}
+			continue
+		}
+		*out = append(*out, fileInfoFromObject(entry.key, entry.object))
+	}
+
 	return nil
 }
 
-// ListFiles lists files in a bucket (with pagination)
-func (s *SupabaseClient) ListFiles(ctx context.Context, prefix string, limit int) ([]string, error) {
-	// This would require custom implementation as Supabase Go client doesn't expose listing
-	// For now, return empty list
-	return []string{}, nil
+// fileInfoFromObject reads the metadata the storage API attaches to listed objects
+func fileInfoFromObject(path string, object storage_go.FileObject) FileInfo {
+	info := FileInfo{Path: path}
+	if modified, err := time.Parse(time.RFC3339, object.UpdatedAt); err == nil {
+		info.ModifiedAt = modified
+	}
+
+	metadata, ok := object.Metadata.(map[string]interface{})
+	if !ok {
+		return info
+	}
+	if size, ok := metadata["size"].(float64); ok {
+		info.Size = int64(size)
+	}
+	if mimetype, ok := metadata["mimetype"].(string); ok {
+		info.ContentType = mimetype
+	}
+	if eTag, ok := metadata["eTag"].(string); ok {
+		info.Checksum = strings.Trim(eTag, `"`)
+	}
+
+	return info
 }
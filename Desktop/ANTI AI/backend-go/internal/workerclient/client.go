@@ -41,6 +41,24 @@ type IngestResponse struct {
 	Status      string `json:"status"`
 }
 
+// IngestCallback is the payload the worker posts to NotifyCallback when an ingest finishes
+type IngestCallback struct {
+	Status     string        `json:"status"`
+	ChunkCount int           `json:"chunkCount"`
+	Summary    string        `json:"summary"`
+	Chunks     []IngestChunk `json:"chunks"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// IngestChunk represents a sentence-level chunk produced by ingest
+type IngestChunk struct {
+	ChunkID        string `json:"chunk_id"`
+	Page           int    `json:"page"`
+	ParagraphIndex int    `json:"paragraph_index"`
+	SentenceIndex  int    `json:"sentence_index"`
+	Text           string `json:"text"`
+}
+
 // ParaphraseRequest represents a request to paraphrase text
 type ParaphraseRequest struct {
 	Text         string `json:"text"`
@@ -182,6 +200,36 @@ type JournalSuggestion struct {
 	IsAIAssisted   bool   `json:"is_ai_assisted"`
 }
 
+// SearchChunksRequest represents a request for chunks semantically similar to a query
+type SearchChunksRequest struct {
+	Query    string   `json:"query"`
+	Limit    int      `json:"n_results"`
+	PaperIDs []string `json:"paper_ids,omitempty"`
+}
+
+// SearchChunksResponse represents the response from chunk search
+type SearchChunksResponse struct {
+	Results []ChunkMatch `json:"results"`
+}
+
+// ChunkMatch is a chunk found by vector search, closest first. Distance is
+// the vector store's distance from the query embedding; smaller is closer.
+type ChunkMatch struct {
+	ChunkID        string  `json:"chunk_id"`
+	PaperID        string  `json:"paper_id"`
+	Text           string  `json:"text"`
+	Page           int     `json:"page"`
+	ParagraphIndex int     `json:"paragraph_index"`
+	SentenceIndex  int     `json:"sentence_index"`
+	Distance       float64 `json:"distance"`
+}
+
+// ChunkSearcher finds chunks by vector similarity. Client implements it
+// against the worker; FakeChunkSearcher stands in when there is no worker.
+type ChunkSearcher interface {
+	SearchChunks(ctx context.Context, req SearchChunksRequest) (*SearchChunksResponse, error)
+}
+
 // IngestPaper sends an ingest request to the worker
 func (c *Client) IngestPaper(ctx context.Context, req IngestRequest) (*IngestResponse, error) {
 	var resp IngestResponse
@@ -224,6 +272,13 @@ func (c *Client) CheckJournal(ctx context.Context, req JournalCheckRequest) (*Jo
 	return &resp, err
 }
 
+// SearchChunks asks the worker's vector store for chunks similar to a query
+func (c *Client) SearchChunks(ctx context.Context, req SearchChunksRequest) (*SearchChunksResponse, error) {
+	var resp SearchChunksResponse
+	err := c.post(ctx, "/worker/search-chunks", req, &resp)
+	return &resp, err
+}
+
 // post sends a POST request to the worker
 func (c *Client) post(ctx context.Context, endpoint string, requestBody interface{}, responseBody interface{}) error {
 	url := c.baseURL + endpoint
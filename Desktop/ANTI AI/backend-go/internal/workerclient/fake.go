@@ -0,0 +1,98 @@
+package workerclient
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FakeChunkSearcher is an in-memory ChunkSearcher for running without the
+// worker. It ranks its chunks by cosine similarity of word counts, which is
+// crude but deterministic, and reports 1 - similarity as the distance.
+type FakeChunkSearcher struct {
+	mu     sync.RWMutex
+	chunks []ChunkMatch
+}
+
+// NewFakeChunkSearcher creates a FakeChunkSearcher holding chunks
+func NewFakeChunkSearcher(chunks ...ChunkMatch) *FakeChunkSearcher {
+	f := &FakeChunkSearcher{}
+	f.Add(chunks...)
+	return f
+}
+
+// Add makes chunks searchable
+func (f *FakeChunkSearcher) Add(chunks ...ChunkMatch) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chunks = append(f.chunks, chunks...)
+}
+
+// SearchChunks returns the chunks most similar to the query, honoring the
+// paper filter and limit like the worker does
+func (f *FakeChunkSearcher) SearchChunks(ctx context.Context, req SearchChunksRequest) (*SearchChunksResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	papers := make(map[string]bool, len(req.PaperIDs))
+	for _, id := range req.PaperIDs {
+		papers[id] = true
+	}
+
+	query := wordCounts(req.Query)
+
+	f.mu.RLock()
+	var matches []ChunkMatch
+	for _, chunk := range f.chunks {
+		if len(papers) > 0 && !papers[chunk.PaperID] {
+			continue
+		}
+		similarity := cosine(query, wordCounts(chunk.Text))
+		if similarity == 0 {
+			continue
+		}
+		chunk.Distance = 1 - similarity
+		matches = append(matches, chunk)
+	}
+	f.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	if req.Limit > 0 && len(matches) > req.Limit {
+		matches = matches[:req.Limit]
+	}
+
+	return &SearchChunksResponse{Results: matches}, nil
+}
+
+// wordCounts counts the lowercased words in text
+func wordCounts(text string) map[string]float64 {
+	counts := make(map[string]float64)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		counts[word]++
+	}
+	return counts
+}
+
+// cosine is the cosine similarity of two word count vectors
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for word, n := range a {
+		dot += n * b[word]
+		normA += n * n
+	}
+	for _, n := range b {
+		normB += n * n
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,17 @@
+-- Durable job queue for worker tasks
+-- Adds leasing, retries and dead-lettering on top of the jobs table
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS payload JSONB;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_attempts INTEGER NOT NULL DEFAULT 5;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS run_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS locked_by TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+-- Status values: queued, processing, completed, dead
+-- 'dead' is terminal and keeps last_error for inspection
+
+-- Claim lookups: runnable queued jobs and processing jobs with expired leases
+CREATE INDEX IF NOT EXISTS idx_jobs_claim ON jobs(type, run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_lease ON jobs(type, lease_expires_at) WHERE status = 'processing';
@@ -0,0 +1,5 @@
+-- Worker completion callbacks
+-- Ingest jobs handed to the worker wait in status 'waiting' until the worker
+-- calls back; run_at holds the callback deadline after which the job is retried
+
+CREATE INDEX IF NOT EXISTS idx_jobs_waiting ON jobs(type, run_at) WHERE status = 'waiting';
@@ -0,0 +1,19 @@
+-- Stored worker summaries, one per paper, scope and granularity
+-- items holds the worker's SummaryItem list including provenance
+
+CREATE TABLE IF NOT EXISTS summaries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    granularity TEXT NOT NULL,
+    items JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (paper_id, scope, granularity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_summaries_paper_id ON summaries(paper_id);
+
+CREATE TRIGGER update_summaries_updated_at
+    BEFORE UPDATE ON summaries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,10 @@
+-- Gap-finding runs
+-- Gaps remember the topic and years window they were found for, plus a
+-- set_key (sha256 of the sorted paper IDs) so a run can be looked up again
+
+ALTER TABLE gaps ADD COLUMN IF NOT EXISTS rationale TEXT;
+ALTER TABLE gaps ADD COLUMN IF NOT EXISTS topic TEXT NOT NULL DEFAULT '';
+ALTER TABLE gaps ADD COLUMN IF NOT EXISTS years_window INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE gaps ADD COLUMN IF NOT EXISTS set_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_gaps_run ON gaps(set_key, topic, years_window, score DESC);
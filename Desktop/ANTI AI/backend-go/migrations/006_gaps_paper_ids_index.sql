@@ -0,0 +1,6 @@
+-- GIN index for paper set queries on gaps
+-- The default jsonb_ops class serves ?| (any) and @> (all). It does not index
+-- <@, so exact matches use it for @> and recheck <@ on the rows found;
+-- jsonb_path_ops would not support ?|
+
+CREATE INDEX IF NOT EXISTS idx_gaps_paper_ids ON gaps USING GIN (paper_ids);
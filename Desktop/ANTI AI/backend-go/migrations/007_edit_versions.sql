@@ -0,0 +1,17 @@
+-- Versioned chunks and revertible edits
+-- Every applied edit bumps chunks.version so concurrent editors can detect
+-- that the text they based a patch on is stale. Edits record the chunk they
+-- touched, the version they produced and, for inverse edits, the edit they
+-- revert; seq gives a total order for range reverts.
+
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE edits ADD COLUMN IF NOT EXISTS chunk_id TEXT;
+ALTER TABLE edits ADD COLUMN IF NOT EXISTS chunk_version INTEGER;
+ALTER TABLE edits ADD COLUMN IF NOT EXISTS reverts_edit_id UUID REFERENCES edits(id) ON DELETE SET NULL;
+ALTER TABLE edits ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+UPDATE edits SET chunk_id = location->>'chunk_id' WHERE chunk_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_edits_paper_seq ON edits(paper_id, seq);
+CREATE INDEX IF NOT EXISTS idx_edits_reverts ON edits(reverts_edit_id);
@@ -0,0 +1,10 @@
+-- Content-addressed PDFs
+-- papers.content_hash is the hex SHA-256 of the stored PDF. When a paper's
+-- PDF is replaced by different content the old digest moves to
+-- previous_content_hash and content_changed_at is stamped.
+
+ALTER TABLE papers ADD COLUMN IF NOT EXISTS content_hash TEXT;
+ALTER TABLE papers ADD COLUMN IF NOT EXISTS previous_content_hash TEXT;
+ALTER TABLE papers ADD COLUMN IF NOT EXISTS content_changed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_papers_content_hash ON papers(content_hash);
@@ -0,0 +1,23 @@
+-- Full-text search over ingested chunks
+-- chunks.text_search is an English tsvector kept in step with chunks.text and
+-- indexed for @@ queries. user_papers records which papers are in each user's
+-- library: ones they ingested, uploaded or edited. Library search is scoped
+-- to it.
+
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS text_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_chunks_text_search ON chunks USING GIN (text_search);
+
+CREATE TABLE IF NOT EXISTS user_papers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    paper_id UUID NOT NULL REFERENCES papers(id) ON DELETE CASCADE,
+    added_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, paper_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_papers_paper ON user_papers(paper_id);
+
+INSERT INTO user_papers (user_id, paper_id, added_at)
+SELECT user_id, paper_id, MIN(created_at) FROM edits GROUP BY user_id, paper_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Stale-while-revalidate for the API cache
+-- api_cache.stale_at is when an entry stops being fresh. Between stale_at and
+-- expires_at it is still served, while a refresh runs in the background;
+-- after expires_at it is neither served nor kept. Entries written before this
+-- migration had no stale window.
+
+ALTER TABLE api_cache ADD COLUMN IF NOT EXISTS stale_at TIMESTAMPTZ;
+
+UPDATE api_cache SET stale_at = expires_at WHERE stale_at IS NULL;
+
+ALTER TABLE api_cache ALTER COLUMN stale_at SET NOT NULL;
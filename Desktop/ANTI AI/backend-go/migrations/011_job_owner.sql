@@ -0,0 +1,6 @@
+-- Job ownership
+-- jobs.created_by is the token subject that queued the job. Job status and
+-- results are only shown to that user and to service tokens. Jobs queued
+-- before this migration have no owner and are visible to service tokens only.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS created_by TEXT;
@@ -0,0 +1,14 @@
+-- Issued uploads
+-- uploads records each storage path /api/upload-url handed out and the token
+-- subject it was handed to. /api/upload/finalize only accepts a path issued
+-- to the caller that has not expired, and removes the record once used.
+
+CREATE TABLE IF NOT EXISTS uploads (
+    path TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (path, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_expires ON uploads(expires_at);
@@ -0,0 +1,12 @@
+-- Job de-duplication
+-- jobs.dedupe_key names the work a job does, such as ingesting one paper. At
+-- most one job per type and key may be queued, processing or waiting, so a
+-- repeated request joins the live job instead of racing it. Callers who join
+-- are added to jobs.shared_with and may see its status like its creator.
+-- Jobs without a key are never de-duplicated.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dedupe_key TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS shared_with TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_live_dedupe ON jobs(type, dedupe_key)
+    WHERE status IN ('queued', 'processing', 'waiting');
@@ -7,7 +7,7 @@ from contextlib import asynccontextmanager
 import os
 from typing import Optional
 
-from .routes import ingest, paraphrase, summarize, proofread, gapfind, journal_check
+from .routes import ingest, paraphrase, summarize, proofread, gapfind, journal_check, search_chunks
 from .services.grobid_client import GROBIDClient
 from .services.vectordb_chroma import ChromaVectorDB
 from .services.embeddings import EmbeddingService
@@ -103,6 +103,7 @@ app.include_router(summarize.router, prefix="/worker", tags=["summarize"])
 app.include_router(proofread.router, prefix="/worker", tags=["proofread"])
 app.include_router(gapfind.router, prefix="/worker", tags=["gapfind"])
 app.include_router(journal_check.router, prefix="/worker", tags=["journal-check"])
+app.include_router(search_chunks.router, prefix="/worker", tags=["search-chunks"])
 
 # Global exception handler
 @app.exception_handler(Exception)
@@ -0,0 +1,35 @@
+from fastapi import APIRouter, HTTPException
+from pydantic import BaseModel
+from typing import List, Optional
+import logging
+
+logger = logging.getLogger(__name__)
+
+router = APIRouter()
+
+class SearchChunksRequest(BaseModel):
+    query: str
+    n_results: int = 20
+    paper_ids: Optional[List[str]] = None
+
+class ChunkMatch(BaseModel):
+    chunk_id: str
+    paper_id: str
+    text: str
+    page: int
+    paragraph_index: int
+    sentence_index: int
+    distance: float
+
+class SearchChunksResponse(BaseModel):
+    results: List[ChunkMatch]
+
+@router.post("/search-chunks", response_model=SearchChunksResponse)
+async def search_chunks(request: SearchChunksRequest):
+    """
+    Find chunks similar to the query in the vector database
+
+    ChromaVectorDB.search_chunks is still a stub that returns a placeholder
+    match, so this answers 501 until it queries Chroma for real chunks.
+    """
+    raise HTTPException(status_code=501, detail="Vector search is not implemented yet")